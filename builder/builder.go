@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"regexp"
 	"strings"
 )
 
@@ -14,25 +13,41 @@ var (
 	ErrUnsupportedOperator       = errors.New("[builder] unsupported operator")
 	errOrValueType               = errors.New(`[builder] the value of "_or" must be of slice of map[string]interface{} type`)
 	errOrderByValueType          = errors.New(`[builder] the value of "_orderby" must be of string type`)
-	errGroupByValueType          = errors.New(`[builder] the value of "_groupby" must be of string type`)
+	errGroupByValueType          = errors.New(`[builder] the value of "_groupby" must be of string, []string or GroupBy type`)
+	errGroupByRollupEmpty        = errors.New(`[builder] "_groupby" WITH ROLLUP requires at least one column`)
 	errLimitValueType            = errors.New(`[builder] the value of "_limit" must be of []uint type`)
 	errLimitValueLength          = errors.New(`[builder] the value of "_limit" must contain one or two uint elements`)
 	errHavingValueType           = errors.New(`[builder] the value of "_having" must be of map[string]interface{}`)
 	errHavingUnsupportedOperator = errors.New(`[builder] "_having" contains unsupported operator`)
+	errHavingWithoutGroupBy      = errors.New(`[builder] "_having" requires a non-empty "_groupby"`)
 	errLockModeValueType         = errors.New(`[builder] the value of "_lockMode" must be of string type`)
 	errNotAllowedLockMode        = errors.New(`[builder] the value of "_lockMode" is not allowed`)
 	errLimitType                 = errors.New(`[builder] the value of "_limit" must be one of int,uint,int64,uint64`)
 	errCustomValueType           = errors.New(`[builder] the value of "_custom_" must impl Comparable`)
+	errChunkMultipleIn           = errors.New(`[builder] only one IN list can be split into chunks`)
+	errDistinctValueType         = errors.New(`[builder] the value of "_distinct" must be of bool type`)
+	errModifierValueType         = errors.New(`[builder] the value of "_modifier" must be of string or []string type`)
+	errTupleValueType            = errors.New(`[builder] the value of "(a,b) in" must be a slice of tuples, e.g. [][]interface{}`)
 
 	errWhereInterfaceSliceType = `[builder] the value of "xxx %s" must be of []interface{} type`
 	errEmptySliceCondition     = `[builder] the value of "%s" must contain at least one element`
+	errGroupByColumn           = `[builder] "%s" is not a valid column of "_groupby"`
+	errHavingUnsupportedKey    = `[builder] "%s" is not supported in "_having"`
+	errChunkUnsupportedKey     = `[builder] "%s" can't be used when an IN list is split into chunks`
+	errSelectExprArgs          = `[builder] select expression "%s" has %d placeholders but %d args`
 
 	defaultIgnoreKeys = map[string]struct{}{
-		"_orderby":  struct{}{},
-		"_groupby":  struct{}{},
-		"_having":   struct{}{},
-		"_limit":    struct{}{},
-		"_lockMode": struct{}{},
+		"_orderby":     struct{}{},
+		"_groupby":     struct{}{},
+		"_having":      struct{}{},
+		"_limit":       struct{}{},
+		"_lockMode":    struct{}{},
+		"_index":       struct{}{},
+		"_hint":        struct{}{},
+		"_distinct":    struct{}{},
+		"_modifier":    struct{}{},
+		"_unscoped":    struct{}{},
+		AllowFullTable: struct{}{},
 	}
 )
 
@@ -71,6 +86,9 @@ func BuildSelect(table string, where map[string]interface{}, selectField []strin
 	var groupBy string
 	var having map[string]interface{}
 	var lockMode string
+	if where, err = applyScope(table, where); nil != err {
+		return
+	}
 	if val, ok := where["_orderby"]; ok {
 		s, ok := val.(string)
 		if !ok {
@@ -80,19 +98,18 @@ func BuildSelect(table string, where map[string]interface{}, selectField []strin
 		orderBy = strings.TrimSpace(s)
 	}
 	if val, ok := where["_groupby"]; ok {
-		s, ok := val.(string)
-		if !ok {
-			err = errGroupByValueType
+		if groupBy, err = resolveGroupBy(val); nil != err {
 			return
 		}
-		groupBy = strings.TrimSpace(s)
-		if "" != groupBy {
-			if h, ok := where["_having"]; ok {
-				having, err = resolveHaving(h)
-				if nil != err {
-					return
-				}
-			}
+	}
+	if h, ok := where["_having"]; ok {
+		if "" == groupBy {
+			err = errHavingWithoutGroupBy
+			return
+		}
+		having, err = resolveHaving(h)
+		if nil != err {
+			return
 		}
 	}
 	if val, ok := where["_limit"]; ok {
@@ -122,11 +139,24 @@ func BuildSelect(table string, where map[string]interface{}, selectField []strin
 			return
 		}
 		lockMode = strings.TrimSpace(s)
-		if _, ok := allowedLockMode[lockMode]; !ok {
-			err = errNotAllowedLockMode
+		if _, err = resolveLockMode(lockMode); nil != err {
 			return
 		}
 	}
+	var extra selectExtra
+	if val, ok := where["_index"]; ok {
+		if extra.indexHint, err = resolveIndexHint(val); nil != err {
+			return
+		}
+	}
+	if val, ok := where["_hint"]; ok {
+		if extra.optimizerHint, err = resolveOptimizerHint(val); nil != err {
+			return
+		}
+	}
+	if extra.modifiers, err = resolveSelectModifier(where["_distinct"], where["_modifier"]); nil != err {
+		return
+	}
 	conditions, err := getWhereConditions(where, defaultIgnoreKeys)
 	if nil != err {
 		return
@@ -140,7 +170,119 @@ func BuildSelect(table string, where map[string]interface{}, selectField []strin
 		conditions = append(conditions, nilComparable(0))
 		conditions = append(conditions, havingCondition...)
 	}
-	return buildSelect(table, selectField, groupBy, orderBy, lockMode, limit, conditions...)
+	return buildSelect(table, selectField, extra, groupBy, orderBy, lockMode, limit, conditions...)
+}
+
+// SelectExpr is a select field with args of its own, e.g. the relevance of MatchAgainst.Score
+type SelectExpr struct {
+	Expr string
+	Args []interface{}
+}
+
+// BuildSelectExpr works like BuildSelect, and exprs are selected after selectField.
+// args of exprs come before those of where in the returned vals, as their placeholders do in the sql
+func BuildSelectExpr(table string, where map[string]interface{}, selectField []string, exprs ...SelectExpr) (string, []interface{}, error) {
+	fields := make([]string, 0, len(selectField)+len(exprs))
+	fields = append(fields, selectField...)
+	var exprVals []interface{}
+	for _, expr := range exprs {
+		if n := countPlaceholders(expr.Expr); n != len(expr.Args) {
+			return "", nil, fmt.Errorf(errSelectExprArgs, expr.Expr, n, len(expr.Args))
+		}
+		fields = append(fields, expr.Expr)
+		exprVals = append(exprVals, expr.Args...)
+	}
+	cond, vals, err := BuildSelect(table, where, fields)
+	if nil != err {
+		return "", nil, err
+	}
+	return cond, append(exprVals, vals...), nil
+}
+
+// countPlaceholders counts the placeholders of sql outside quoted literals and comments
+func countPlaceholders(sql string) int {
+	var n int
+	for _, tok := range tokenizeSQL(sql) {
+		if tok.significant && "?" == tok.text {
+			n++
+		}
+	}
+	return n
+}
+
+// BuildSelectChunks works like BuildSelect, but if InListChunk is set by SetInListLimit and
+// the where map contains an IN list longer than the threshold, the query is split into
+// several ones each taking at most threshold elements of the list.
+// only one such list is allowed, and _orderby,_groupby,_having,_limit are rejected
+// because they can't be applied across chunks
+func BuildSelectChunks(table string, where map[string]interface{}, selectField []string) ([]string, [][]interface{}, error) {
+	c := loadConfig()
+	key, list, err := findLargeInList(c, where)
+	if nil != err {
+		return nil, nil, err
+	}
+	if nil == list {
+		cond, vals, err := BuildSelect(table, where, selectField)
+		if nil != err {
+			return nil, nil, err
+		}
+		return []string{cond}, [][]interface{}{vals}, nil
+	}
+	var conds []string
+	var chunkVals [][]interface{}
+	for begin := 0; begin < len(list); begin += c.inListThreshold {
+		end := begin + c.inListThreshold
+		if end > len(list) {
+			end = len(list)
+		}
+		chunk := copyWhere(where)
+		chunk[key] = list[begin:end]
+		fields := append([]string(nil), selectField...)
+		cond, vals, err := BuildSelect(table, chunk, fields)
+		if nil != err {
+			return nil, nil, err
+		}
+		conds = append(conds, cond)
+		chunkVals = append(chunkVals, vals)
+	}
+	return conds, chunkVals, nil
+}
+
+func findLargeInList(c *config, where map[string]interface{}) (string, []interface{}, error) {
+	if c.inListStrategy != InListChunk {
+		return "", nil, nil
+	}
+	var key string
+	var list []interface{}
+	for k, val := range where {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		_, operator, err := splitKey(k, val)
+		if nil != err {
+			return "", nil, err
+		}
+		if strings.ToLower(operator) != opIn {
+			continue
+		}
+		vals, ok := convertInterfaceToMap(val)
+		if !ok || !c.isLargeInList(len(vals)) {
+			continue
+		}
+		if nil != list {
+			return "", nil, errChunkMultipleIn
+		}
+		key, list = k, vals
+	}
+	if nil == list {
+		return "", nil, nil
+	}
+	for _, k := range []string{"_orderby", "_groupby", "_having", "_limit"} {
+		if _, ok := where[k]; ok {
+			return "", nil, fmt.Errorf(errChunkUnsupportedKey, k)
+		}
+	}
+	return key, list, nil
 }
 
 func copyWhere(src map[string]interface{}) (target map[string]interface{}) {
@@ -151,24 +293,125 @@ func copyWhere(src map[string]interface{}) (target map[string]interface{}) {
 	return
 }
 
+// resolveGroupBy translates the value of _groupby into the GROUP BY list,
+// which is a comma separated string, a slice of columns or a GroupBy.
+// The string is used as it is like before, only the columns of a slice or GroupBy are validated
+func resolveGroupBy(val interface{}) (string, error) {
+	var g GroupBy
+	switch v := val.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case []string:
+		g.Columns = v
+	case GroupBy:
+		g = v
+	default:
+		return "", errGroupByValueType
+	}
+	columns := make([]string, 0, len(g.Columns))
+	for _, col := range g.Columns {
+		col = strings.TrimSpace(col)
+		if !isValidGroupByColumn(col) {
+			return "", fmt.Errorf(errGroupByColumn, col)
+		}
+		columns = append(columns, col)
+	}
+	if 0 == len(columns) {
+		if g.WithRollup {
+			return "", errGroupByRollupEmpty
+		}
+		return "", nil
+	}
+	groupBy := strings.Join(columns, ",")
+	if g.WithRollup {
+		groupBy += " WITH ROLLUP"
+	}
+	return groupBy, nil
+}
+
+// isValidGroupByColumn accepts columns and expressions like DATE_FORMAT(created_at, '%Y-%m'),
+// but nothing able to end the statement or start a comment
+func isValidGroupByColumn(col string) bool {
+	if "" == col {
+		return false
+	}
+	depth := 0
+	for _, tok := range tokenizeSQL(col) {
+		text := tok.text
+		switch {
+		case !tok.significant:
+			// comments are insignificant as well as spaces
+			if "" != strings.TrimSpace(text) {
+				return false
+			}
+		case '\'' == text[0] || '"' == text[0] || '`' == text[0]:
+			// a backslash may escape the closing quote, e.g. '\', so the literal must not contain any
+			if len(text) < 2 || text[len(text)-1] != text[0] || strings.IndexByte(text, '\\') >= 0 {
+				return false
+			}
+		case "(" == text:
+			depth++
+		case ")" == text:
+			if depth--; depth < 0 {
+				return false
+			}
+		case ";" == text:
+			return false
+		}
+	}
+	return 0 == depth
+}
+
+// resolveHaving checks the value of _having, which supports the grammar of where
+// except the special keys other than _or and _custom_
 func resolveHaving(having interface{}) (map[string]interface{}, error) {
-	var havingMap map[string]interface{}
-	var ok bool
-	if havingMap, ok = having.(map[string]interface{}); !ok {
+	havingMap, ok := having.(map[string]interface{})
+	if !ok {
 		return nil, errHavingValueType
 	}
-	copiedMap := make(map[string]interface{})
-	for key, val := range havingMap {
+	if err := checkHaving(havingMap); nil != err {
+		return nil, err
+	}
+	return copyWhere(havingMap), nil
+}
+
+func checkHaving(having map[string]interface{}) error {
+	for key, val := range having {
+		if strings.HasPrefix(key, "_or") {
+			orWheres, ok := val.([]map[string]interface{})
+			if !ok {
+				return errOrValueType
+			}
+			for _, orWhere := range orWheres {
+				if err := checkHaving(orWhere); nil != err {
+					return err
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "_custom_") {
+			continue
+		}
+		if strings.HasPrefix(key, "_") {
+			return fmt.Errorf(errHavingUnsupportedKey, key)
+		}
 		_, operator, err := splitKey(key, val)
 		if nil != err {
-			return nil, err
+			return err
+		}
+		operator = strings.ToLower(operator)
+		if isStringInSlice(operator, opOrder) {
+			continue
 		}
-		if !isStringInSlice(strings.ToLower(operator), opOrder) {
-			return nil, errHavingUnsupportedOperator
+		op, ok := lookupOperator(operator)
+		if !ok {
+			return errHavingUnsupportedOperator
+		}
+		if err = op.checkValue(val); nil != err {
+			return err
 		}
-		copiedMap[key] = val
 	}
-	return copiedMap, nil
+	return nil
 }
 
 func getLimit(where map[string]interface{}) (uint, error) {
@@ -192,6 +435,10 @@ func getLimit(where map[string]interface{}) (uint, error) {
 
 // BuildUpdate work as its name says
 func BuildUpdate(table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
+	where, err := applyScope(table, where)
+	if nil != err {
+		return "", nil, err
+	}
 	limit, err := getLimit(where)
 	if err != nil {
 		return "", nil, err
@@ -203,8 +450,36 @@ func BuildUpdate(table string, where map[string]interface{}, update map[string]i
 	return buildUpdate(table, update, limit, conditions...)
 }
 
-// BuildDelete work as its name says
+// BuildBulkUpdate updates many rows with different values in one statement,
+// rows maps the value of key column to the update of that row:
+// UPDATE table SET col=CASE key WHEN ? THEN ? WHEN ? THEN ? ELSE col END WHERE (key IN (?,?) AND ...)
+// where works as in BuildUpdate but _limit is ignored
+func BuildBulkUpdate(table, key string, rows map[interface{}]map[string]interface{}, where map[string]interface{}) (string, []interface{}, error) {
+	where, err := applyScope(table, where)
+	if nil != err {
+		return "", nil, err
+	}
+	conditions, err := getWhereConditions(where, defaultIgnoreKeys)
+	if nil != err {
+		return "", nil, err
+	}
+	return buildBulkUpdate(table, key, rows, conditions...)
+}
+
+// BuildDelete work as its name says,
+// it builds an UPDATE instead if the table's Scope has SoftDelete
 func BuildDelete(table string, where map[string]interface{}) (string, []interface{}, error) {
+	scope, err := lookupScope(table, where)
+	if nil != err {
+		return "", nil, err
+	}
+	if nil != scope && len(scope.SoftDelete) > 0 {
+		return BuildUpdate(table, where, scope.SoftDelete)
+	}
+	where, err = applyScope(table, where)
+	if nil != err {
+		return "", nil, err
+	}
 	limit, err := getLimit(where)
 	if err != nil {
 		return "", nil, err
@@ -236,6 +511,15 @@ func BuildInsertOnDuplicate(table string, data []map[string]interface{}, update
 	return buildInsertOnDuplicate(table, data, update)
 }
 
+// BuildUpsert works like BuildInsertOnDuplicate, and updates all inserted columns except keys
+// with the value of the inserted row:
+// INSERT INTO table (id,name,age) VALUES (?,?,?) ON DUPLICATE KEY UPDATE age=VALUES(age),name=VALUES(name)
+// or with the row alias if the dialect supports RowAlias:
+// INSERT INTO table (id,name,age) VALUES (?,?,?) AS new ON DUPLICATE KEY UPDATE age=new.age,name=new.name
+func BuildUpsert(table string, data []map[string]interface{}, keys ...string) (string, []interface{}, error) {
+	return buildUpsert(table, data, keys)
+}
+
 func isStringInSlice(str string, arr []string) bool {
 	for _, s := range arr {
 		if s == str {
@@ -300,11 +584,32 @@ func getWhereConditions(where map[string]interface{}, ignoreKeys map[string]stru
 			return nil, err
 		}
 		operator = strings.ToLower(operator)
+		if strings.HasPrefix(field, "(") && (operator == opIn || operator == opNotIn) {
+			tuple, err := resolveTupleIn(field, operator, val)
+			if nil != err {
+				return nil, err
+			}
+			comparables = append(comparables, tuple)
+			continue
+		}
 		if !isStringInSlice(operator, opOrder) {
-			return nil, ErrUnsupportedOperator
+			op, ok := lookupOperator(operator)
+			if !ok {
+				return nil, ErrUnsupportedOperator
+			}
+			if err = op.checkValue(val); nil != err {
+				return nil, err
+			}
 		}
 		if _, ok := val.(NullType); ok {
 			operator = opNull
+		} else if loadConfig().nilAsNull && isNilValue(val) {
+			switch operator {
+			case opEq:
+				operator, val = opNull, IsNull
+			case opNe1, opNe2:
+				operator, val = opNull, IsNotNull
+			}
 		}
 		wms.add(operator, field, val)
 	}
@@ -316,8 +621,27 @@ func getWhereConditions(where map[string]interface{}, ignoreKeys map[string]stru
 	return comparables, nil
 }
 
+// SetNilAsNull makes builder translate nil values into IS NULL for = and IS NOT NULL for !=,<>,
+// and check nil in the value of in and not in by IS NULL and IS NOT NULL,
+// otherwise they are bound as they are and `= NULL` never matches.
+// It changes the meaning of every where built afterwards in the process, turn it on at startup
+func SetNilAsNull(on bool) {
+	updateConfig(func(c *config) {
+		c.nilAsNull = on
+	})
+}
+
+func isNilValue(val interface{}) bool {
+	if nil == val {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
 const (
 	opEq         = "="
+	opNullSafeEq = "<=>"
 	opNe1        = "!="
 	opNe2        = "<>"
 	opIn         = "in"
@@ -330,6 +654,10 @@ const (
 	opNotLike    = "not like"
 	opBetween    = "between"
 	opNotBetween = "not between"
+	// full-text search, the field is a comma separated column list
+	opMatch          = "match"
+	opMatchBoolean   = "match in boolean mode"
+	opMatchExpansion = "match with query expansion"
 	// special
 	opNull = "null"
 )
@@ -340,6 +668,9 @@ var op2Comparable = map[string]compareProducer{
 	opEq: func(m map[string]interface{}) (Comparable, error) {
 		return Eq(m), nil
 	},
+	opNullSafeEq: func(m map[string]interface{}) (Comparable, error) {
+		return NullSafeEq(m), nil
+	},
 	opNe1: func(m map[string]interface{}) (Comparable, error) {
 		return Ne(m), nil
 	},
@@ -392,12 +723,21 @@ var op2Comparable = map[string]compareProducer{
 	opNotLike: func(m map[string]interface{}) (Comparable, error) {
 		return NotLike(m), nil
 	},
+	opMatch: func(m map[string]interface{}) (Comparable, error) {
+		return fullTextMap{mode: NaturalLanguageMode, m: m}, nil
+	},
+	opMatchBoolean: func(m map[string]interface{}) (Comparable, error) {
+		return fullTextMap{mode: BooleanMode, m: m}, nil
+	},
+	opMatchExpansion: func(m map[string]interface{}) (Comparable, error) {
+		return fullTextMap{mode: QueryExpansionMode, m: m}, nil
+	},
 	opNull: func(m map[string]interface{}) (Comparable, error) {
 		return nullCompareble(m), nil
 	},
 }
 
-var opOrder = []string{opEq, opIn, opNe1, opNe2, opNotIn, opGt, opGte, opLt, opLte, opLike, opNotLike, opBetween, opNotBetween, opNull}
+var opOrder = []string{opEq, opNullSafeEq, opIn, opNe1, opNe2, opNotIn, opGt, opGte, opLt, opLte, opLike, opNotLike, opBetween, opNotBetween, opMatch, opMatchBoolean, opMatchExpansion, opNull}
 
 func buildWhereCondition(mapSet *whereMapSet) ([]Comparable, error) {
 	var cpArr []Comparable
@@ -416,6 +756,13 @@ func buildWhereCondition(mapSet *whereMapSet) ([]Comparable, error) {
 		}
 		cpArr = append(cpArr, cp)
 	}
+	for _, op := range registeredOperators() {
+		whereMap, ok := mapSet.set[op.Name]
+		if !ok {
+			continue
+		}
+		cpArr = append(cpArr, operatorComparable{op: op, m: whereMap})
+	}
 	return cpArr, nil
 }
 
@@ -434,6 +781,32 @@ func convertWhereMapToWhereMapSlice(where map[string]interface{}, op string) (ma
 	return result, nil
 }
 
+// resolveTupleIn resolves keys like "(a,b) in" whose value is a slice of tuples
+func resolveTupleIn(field, op string, val interface{}) (Comparable, error) {
+	columns := strings.Split(strings.TrimSuffix(strings.TrimPrefix(field, "("), ")"), ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	tuples, ok := convertInterfaceToMap(val)
+	if !ok {
+		return nil, fmt.Errorf(errWhereInterfaceSliceType, op)
+	}
+	if 0 == len(tuples) {
+		return nil, fmt.Errorf(errEmptySliceCondition, op)
+	}
+	values := make([][]interface{}, len(tuples))
+	for i, tuple := range tuples {
+		values[i], ok = convertInterfaceToMap(tuple)
+		if !ok {
+			return nil, errTupleValueType
+		}
+	}
+	if op == opNotIn {
+		return NotInTuple(columns, values...)
+	}
+	return InTuple(columns, values...)
+}
+
 func convertInterfaceToMap(val interface{}) ([]interface{}, bool) {
 	s := reflect.ValueOf(val)
 	if s.Kind() != reflect.Slice {
@@ -452,7 +825,7 @@ func splitKey(key string, val interface{}) (field string, operator string, err e
 		err = errSplitEmptyKey
 		return
 	}
-	idx := strings.IndexByte(key, ' ')
+	idx := indexSpaceOutsideParens(key)
 	if idx == -1 {
 		field = key
 		operator = "="
@@ -467,59 +840,105 @@ func splitKey(key string, val interface{}) (field string, operator string, err e
 	return
 }
 
+// indexSpaceOutsideParens works like strings.IndexByte(s, ' ') but skips spaces
+// inside parentheses and around commas, so that fields like "(a, b)" and
+// column lists like "title, body" stay in one piece
+func indexSpaceOutsideParens(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ' ':
+			if 0 == depth && !besideComma(s, i) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// besideComma reports whether the run of spaces containing s[i] follows or precedes a comma
+func besideComma(s string, i int) bool {
+	prev := strings.TrimRight(s[:i], " ")
+	next := strings.TrimLeft(s[i:], " ")
+	return strings.HasSuffix(prev, ",") || strings.HasPrefix(next, ",")
+}
+
 func removeInnerSpace(operator string) string {
-	n := len(operator)
-	firstSpace := strings.IndexByte(operator, ' ')
-	if firstSpace == -1 {
+	if strings.IndexByte(operator, ' ') == -1 {
 		return operator
 	}
-	lastSpace := firstSpace
-	for i := firstSpace + 1; i < n; i++ {
-		if operator[i] == ' ' {
-			lastSpace = i
-		} else {
-			break
-		}
-	}
-	return operator[:firstSpace] + operator[lastSpace:]
+	return strings.Join(strings.Fields(operator), " ")
 }
 
 const (
 	paramPlaceHolder = "?"
 )
 
-var searchHandle = regexp.MustCompile(`{{\S+?}}`)
-
-// NamedQuery is used for expressing complex query
+// NamedQuery is used for expressing complex query.
+// params can be written as {{name}}, :name or @name, those in quoted strings and comments are ignored.
+// @name is left as a user variable if name isn't in data, and :: is a cast rather than a param.
+// a slice is expanded into (?,?,?) and must not be empty, nil and []byte are bound as they are.
+// all missing params are reported in one error. the parsed sql is cached for reuse.
+// a param whose value is a Comparable or a where map is expanded into a condition.
+// [[ ... ]] is an optional block which is only emitted if all params in it are present and non-empty,
+// AND,OR,WHERE left dangling by omitted blocks are removed
 func NamedQuery(sql string, data map[string]interface{}) (string, []interface{}, error) {
-	length := len(data)
-	if length == 0 {
-		return sql, nil, nil
+	t, err := getNamedTemplate(sql)
+	if nil != err {
+		return "", nil, err
 	}
-	vals := make([]interface{}, 0, length)
-	var err error
-	cond := searchHandle.ReplaceAllStringFunc(sql, func(paramName string) string {
-		paramName = strings.TrimRight(strings.TrimLeft(paramName, "{"), "}")
-		val, ok := data[paramName]
-		if !ok {
-			err = fmt.Errorf("%s not found", paramName)
-			return ""
-		}
-		v := reflect.ValueOf(val)
-		if v.Type().Kind() != reflect.Slice {
-			vals = append(vals, val)
-			return paramPlaceHolder
-		}
-		length := v.Len()
-		for i := 0; i < length; i++ {
-			vals = append(vals, v.Index(i).Interface())
-		}
-		return createMultiPlaceholders(length)
-	})
+	return t.bind(data)
+}
+
+// NamedQueryStruct works like NamedQuery but data can also be a struct, whose fields are named
+// by the ddb tag or the field name. If data is a slice of structs or maps, the group after VALUES
+// is repeated for every element, e.g. `INSERT INTO tb (name,age) VALUES (:name,:age)`
+func NamedQueryStruct(sql string, data interface{}) (string, []interface{}, error) {
+	t, err := getNamedTemplate(sql)
 	if nil != err {
 		return "", nil, err
 	}
-	return cond, vals, nil
+	return t.bindStruct(data)
+}
+
+// Template is a NamedQuery sql parsed once, which can be bound many times.
+// It's safe for concurrent use
+type Template struct {
+	t *namedTemplate
+}
+
+// Compile parses sql in the syntax of NamedQuery into a Template
+func Compile(sql string) (*Template, error) {
+	t, err := parseNamed(sql)
+	if nil != err {
+		return nil, err
+	}
+	return &Template{t: t}, nil
+}
+
+// MustCompile is like Compile but panics if sql can't be parsed
+func MustCompile(sql string) *Template {
+	t, err := Compile(sql)
+	if nil != err {
+		panic(err)
+	}
+	return t
+}
+
+// Bind works like NamedQuery without parsing the sql again
+func (t *Template) Bind(data map[string]interface{}) (string, []interface{}, error) {
+	return t.t.bind(data)
+}
+
+// BindStruct works like NamedQueryStruct without parsing the sql again
+func (t *Template) BindStruct(data interface{}) (string, []interface{}, error) {
+	return t.t.bindStruct(data)
 }
 
 func createMultiPlaceholders(num int) string {
@@ -200,9 +200,7 @@ func TestBuildHaving(t *testing.T) {
 				selectField: []string{"name, age"},
 			},
 			out: outStruct{
-				cond: "SELECT name, age FROM tb WHERE (age IN (?,?,?))",
-				vals: []interface{}{1, 2, 3},
-				err:  nil,
+				err: errHavingWithoutGroupBy,
 			},
 		},
 		{
@@ -249,9 +247,7 @@ func TestBuildHaving(t *testing.T) {
 				selectField: []string{"name, age"},
 			},
 			out: outStruct{
-				cond: "SELECT name, age FROM tb WHERE (age IN (?,?,?))",
-				vals: []interface{}{1, 2, 3},
-				err:  nil,
+				err: errHavingWithoutGroupBy,
 			},
 		},
 	}
@@ -326,6 +322,168 @@ func TestBuildHaving_1(t *testing.T) {
 	}
 }
 
+func TestBuildHaving_2(t *testing.T) {
+	var testCases = []struct {
+		having interface{}
+		cond   string
+		vals   []interface{}
+		err    error
+	}{
+		{
+			having: map[string]interface{}{
+				"count(*) >":          10,
+				"sum(price * qty) <=": 5000,
+			},
+			cond: "SELECT name FROM tb GROUP BY name HAVING (count(*)>? AND sum(price * qty)<=?)",
+			vals: []interface{}{10, 5000},
+		},
+		{
+			having: map[string]interface{}{
+				"total >": 0,
+				"_or": []map[string]interface{}{
+					{"avg(score) >=": 90},
+					{"max(score)": 100, "min(score) >": 60},
+				},
+				"_custom_1": Custom("count(DISTINCT uid) > ?", 3),
+			},
+			cond: "SELECT name FROM tb GROUP BY name HAVING (count(DISTINCT uid) > ? AND ((avg(score)>=?) OR (max(score)=? AND min(score)>?)) AND total>?)",
+			vals: []interface{}{3, 90, 100, 60, 0},
+		},
+		{
+			having: map[string]interface{}{"total like": "1%"},
+			cond:   "SELECT name FROM tb GROUP BY name HAVING (total LIKE ?)",
+			vals:   []interface{}{"1%"},
+		},
+		{
+			having: map[string]interface{}{
+				"_or": []map[string]interface{}{
+					{"total >": 1},
+					{"total =~": 2},
+				},
+			},
+			err: errHavingUnsupportedOperator,
+		},
+		{
+			having: map[string]interface{}{"group_concat(tag) regexp": "^a"},
+			cond:   "SELECT name FROM tb GROUP BY name HAVING (group_concat(tag) REGEXP ?)",
+			vals:   []interface{}{"^a"},
+		},
+		{
+			having: map[string]interface{}{
+				"_or": []map[string]interface{}{
+					{"total >": 1},
+					{"any_value(doc) ->>": "$.name"},
+				},
+			},
+			err: errors.New(`[builder] the value of "->>" must be a slice of 2 elements`),
+		},
+		{
+			having: map[string]interface{}{"total >": 1, "_limit": []uint{1}},
+			err:    errors.New(`[builder] "_limit" is not supported in "_having"`),
+		},
+		{
+			having: map[string]interface{}{"_or": map[string]interface{}{"total >": 1}},
+			err:    errOrValueType,
+		},
+		{
+			having: "total > 1",
+			err:    errHavingValueType,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testCases {
+		cond, vals, err := BuildSelect("tb", map[string]interface{}{
+			"_groupby": "name",
+			"_having":  tc.having,
+		}, []string{"name"})
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestBuildGroupBy(t *testing.T) {
+	var testCases = []struct {
+		groupBy interface{}
+		fields  []string
+		cond    string
+		err     error
+	}{
+		{
+			groupBy: " year,country ",
+			fields:  []string{"year", "country", "SUM(profit)"},
+			cond:    "SELECT year,country,SUM(profit) FROM tb WHERE (a=?) GROUP BY year,country",
+		},
+		{
+			groupBy: []string{"year", " DATE(created_at) "},
+			fields:  []string{"year", "SUM(profit)"},
+			cond:    "SELECT year,SUM(profit) FROM tb WHERE (a=?) GROUP BY year,DATE(created_at)",
+		},
+		{
+			groupBy: GroupBy{Columns: []string{"year", "country"}, WithRollup: true},
+			fields:  []string{"year", "country", Grouping("year", "country") + " AS g", "SUM(profit)"},
+			cond:    "SELECT year,country,GROUPING(year,country) AS g,SUM(profit) FROM tb WHERE (a=?) GROUP BY year,country WITH ROLLUP",
+		},
+		{
+			groupBy: []string{},
+			fields:  []string{"year"},
+			cond:    "SELECT year FROM tb WHERE (a=?)",
+		},
+		{
+			groupBy: GroupBy{WithRollup: true},
+			err:     errGroupByRollupEmpty,
+		},
+		{
+			groupBy: []string{"year", ""},
+			err:     errors.New(`[builder] "" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: "DATE_FORMAT(created_at, '%Y-%m')",
+			fields:  []string{"COUNT(*)"},
+			cond:    "SELECT COUNT(*) FROM tb WHERE (a=?) GROUP BY DATE_FORMAT(created_at, '%Y-%m')",
+		},
+		{
+			groupBy: []string{"`year`", "DATE_FORMAT(created_at, '%Y-%m;')"},
+			fields:  []string{"COUNT(*)"},
+			cond:    "SELECT COUNT(*) FROM tb WHERE (a=?) GROUP BY `year`,DATE_FORMAT(created_at, '%Y-%m;')",
+		},
+		{
+			groupBy: []string{"year; DROP TABLE tb"},
+			err:     errors.New(`[builder] "year; DROP TABLE tb" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: []string{`'\'`, `a' UNION SELECT pw FROM users -- x'`},
+			err:     errors.New(`[builder] "'\'" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: []string{`'a\'b'`},
+			err:     errors.New(`[builder] "'a\'b'" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: []string{"year -- x"},
+			err:     errors.New(`[builder] "year -- x" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: []string{"DATE_FORMAT(created_at, '%Y)"},
+			err:     errors.New(`[builder] "DATE_FORMAT(created_at, '%Y)" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: []string{"DATE(created_at"},
+			err:     errors.New(`[builder] "DATE(created_at" is not a valid column of "_groupby"`),
+		},
+		{
+			groupBy: 1,
+			err:     errGroupByValueType,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testCases {
+		cond, _, err := BuildSelect("tb", map[string]interface{}{"a": 1, "_groupby": tc.groupBy}, tc.fields)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+	}
+}
+
 func Test_BuildInsert(t *testing.T) {
 	ass := assert.New(t)
 	type inStruct struct {
@@ -1378,3 +1536,428 @@ func TestInsertOnDuplicate(t *testing.T) {
 	ass.Equal("INSERT INTO tb (a,b,c) VALUES (?,?,?) ON DUPLICATE KEY UPDATE c=?", cond)
 	ass.Equal([]interface{}{1, 2, 3, 4}, vals)
 }
+
+func TestBuildFullText(t *testing.T) {
+	var data = []struct {
+		where map[string]interface{}
+		cond  string
+		vals  []interface{}
+		err   error
+	}{
+		{
+			where: map[string]interface{}{
+				"title,body match": "database",
+				"status":           1,
+			},
+			cond: "SELECT * FROM tb WHERE (status=? AND MATCH(title,body) AGAINST (?))",
+			vals: []interface{}{1, "database"},
+		},
+		{
+			where: map[string]interface{}{
+				"title,body MATCH IN  BOOLEAN MODE": "+mysql -oracle",
+				"tag match with query expansion":    "go",
+			},
+			cond: "SELECT * FROM tb WHERE (MATCH(title,body) AGAINST (? IN BOOLEAN MODE) AND MATCH(tag) AGAINST (? WITH QUERY EXPANSION))",
+			vals: []interface{}{"+mysql -oracle", "go"},
+		},
+		{
+			where: map[string]interface{}{
+				"_or": []map[string]interface{}{
+					{"title match": "mysql"},
+					{"_custom_0": Match("mysql", BooleanMode, "body")},
+				},
+			},
+			cond: "SELECT * FROM tb WHERE (((MATCH(title) AGAINST (?)) OR (MATCH(body) AGAINST (? IN BOOLEAN MODE))))",
+			vals: []interface{}{"mysql", "mysql"},
+		},
+		{
+			where: map[string]interface{}{
+				"title, body ,tag  match": "database",
+			},
+			cond: "SELECT * FROM tb WHERE (MATCH(title,body,tag) AGAINST (?))",
+			vals: []interface{}{"database"},
+		},
+		{
+			where: map[string]interface{}{
+				"title match in natural": "mysql",
+			},
+			err: ErrUnsupportedOperator,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := BuildSelect("tb", tc.where, nil)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestBuildTupleIn(t *testing.T) {
+	var data = []struct {
+		where map[string]interface{}
+		cond  string
+		vals  []interface{}
+		err   error
+	}{
+		{
+			where: map[string]interface{}{
+				"(tenant_id, user_id)": [][]interface{}{{1, 2}, {1, 3}},
+				"status":               1,
+			},
+			cond: "SELECT * FROM tb WHERE ((tenant_id,user_id) IN ((?,?),(?,?)) AND status=?)",
+			vals: []interface{}{1, 2, 1, 3, 1},
+		},
+		{
+			where: map[string]interface{}{
+				"(a,b) not in": [][]int{{1, 2}},
+			},
+			cond: "SELECT * FROM tb WHERE ((a,b) NOT IN ((?,?)))",
+			vals: []interface{}{1, 2},
+		},
+		{
+			where: map[string]interface{}{
+				"(a,b) in": [][]interface{}{{1, 2}, {3}},
+			},
+			err: errors.New("[builder] tuple in requires 2 values per tuple but got 1"),
+		},
+		{
+			where: map[string]interface{}{
+				"(a,b) in": []interface{}{1, 2},
+			},
+			err: errTupleValueType,
+		},
+		{
+			where: map[string]interface{}{
+				"(a,b) in": [][]interface{}{},
+			},
+			err: errors.New(`[builder] the value of "in" must contain at least one element`),
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := BuildSelect("tb", tc.where, nil)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestBuildSelectChunks(t *testing.T) {
+	ass := assert.New(t)
+	where := map[string]interface{}{
+		"id in":  []int{1, 2, 3, 4, 5},
+		"status": 1,
+	}
+	conds, vals, err := BuildSelectChunks("tb", where, []string{"id"})
+	ass.NoError(err)
+	ass.Equal([]string{"SELECT id FROM tb WHERE (status=? AND id IN (?,?,?,?,?))"}, conds)
+	ass.Equal([][]interface{}{{1, 1, 2, 3, 4, 5}}, vals)
+
+	SetInListLimit(2, InListChunk)
+	defer SetInListLimit(0, InListExpand)
+	conds, vals, err = BuildSelectChunks("tb", where, []string{"id"})
+	ass.NoError(err)
+	ass.Equal([]string{
+		"SELECT id FROM tb WHERE (status=? AND id IN (?,?))",
+		"SELECT id FROM tb WHERE (status=? AND id IN (?,?))",
+		"SELECT id FROM tb WHERE (status=? AND id IN (?))",
+	}, conds)
+	ass.Equal([][]interface{}{{1, 1, 2}, {1, 3, 4}, {1, 5}}, vals)
+
+	_, _, err = BuildSelectChunks("tb", map[string]interface{}{
+		"id":  []int{1, 2, 3},
+		"uid": []int{1, 2, 3},
+	}, nil)
+	ass.Equal(errChunkMultipleIn, err)
+	_, _, err = BuildSelectChunks("tb", map[string]interface{}{
+		"id":     []int{1, 2, 3},
+		"_limit": []uint{10},
+	}, nil)
+	ass.Equal(errors.New(`[builder] "_limit" can't be used when an IN list is split into chunks`), err)
+}
+
+func TestBuildNullHandling(t *testing.T) {
+	var nilPtr *int
+	var data = []struct {
+		nilAsNull bool
+		where     map[string]interface{}
+		cond      string
+		vals      []interface{}
+	}{
+		{
+			where: map[string]interface{}{"deleted_at": nil},
+			cond:  "SELECT * FROM tb WHERE (deleted_at=?)",
+			vals:  []interface{}{nil},
+		},
+		{
+			nilAsNull: true,
+			where: map[string]interface{}{
+				"deleted_at": nil,
+				"parent_id":  nilPtr,
+				"owner <>":   nil,
+				"age >":      nil,
+			},
+			cond: "SELECT * FROM tb WHERE (age>? AND deleted_at IS NULL AND owner IS NOT NULL AND parent_id IS NULL)",
+			vals: []interface{}{nil},
+		},
+		{
+			nilAsNull: true,
+			where: map[string]interface{}{
+				"_or": []map[string]interface{}{
+					{"deleted_at": nil},
+					{"deleted_at >": 100},
+				},
+			},
+			cond: "SELECT * FROM tb WHERE (((deleted_at IS NULL) OR (deleted_at>?)))",
+			vals: []interface{}{100},
+		},
+		{
+			where: map[string]interface{}{
+				"a <=>": nil,
+				"b <=>": 1,
+			},
+			cond: "SELECT * FROM tb WHERE (a<=>? AND b<=>?)",
+			vals: []interface{}{nil, 1},
+		},
+		{
+			nilAsNull: true,
+			where: map[string]interface{}{
+				"a in":     []interface{}{1, nil, 2},
+				"b not in": []interface{}{nil, 3},
+				"c in":     []interface{}{nil},
+				"d in":     []interface{}{nilPtr, 4},
+			},
+			cond: "SELECT * FROM tb WHERE ((a IN (?,?) OR a IS NULL) AND c IS NULL AND (d IN (?) OR d IS NULL) AND (b NOT IN (?) AND b IS NOT NULL))",
+			vals: []interface{}{1, 2, 4, 3},
+		},
+		{
+			where: map[string]interface{}{
+				"a in":     []interface{}{1, nil},
+				"b not in": []interface{}{nil},
+			},
+			cond: "SELECT * FROM tb WHERE (a IN (?,?) AND b NOT IN (?))",
+			vals: []interface{}{1, nil, nil},
+		},
+	}
+	ass := assert.New(t)
+	defer SetNilAsNull(false)
+	for _, tc := range data {
+		SetNilAsNull(tc.nilAsNull)
+		cond, vals, err := BuildSelect("tb", tc.where, nil)
+		ass.NoError(err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestBuildUpdateExpr(t *testing.T) {
+	ass := assert.New(t)
+	cond, vals, err := BuildUpdate("tb", map[string]interface{}{"id": 1}, map[string]interface{}{
+		"stock":       Decr(2),
+		"sold":        Incr(2),
+		"price":       Expr("price*?+?", 1.1, 5),
+		"first_login": Coalesce(100),
+		"max_score":   Greatest(98),
+		"min_price":   Least(10),
+	})
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET first_login=COALESCE(first_login,?),max_score=GREATEST(max_score,?),min_price=LEAST(min_price,?),price=price*?+?,sold=sold+?,stock=stock-? WHERE (id=?)", cond)
+	ass.Equal([]interface{}{100, 98, 10, 1.1, 5, 2, 2, 1}, vals)
+}
+
+func TestBuildBulkUpdate(t *testing.T) {
+	ass := assert.New(t)
+	cond, vals, err := BuildBulkUpdate("tb", "id", map[interface{}]map[string]interface{}{
+		10: {"name": "a", "age": 20},
+		2:  {"name": "b"},
+		3:  {"age": Raw("age+1")},
+	}, map[string]interface{}{"status": 1})
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET age=CASE id WHEN ? THEN age+1 WHEN ? THEN ? ELSE age END,name=CASE id WHEN ? THEN ? WHEN ? THEN ? ELSE name END WHERE (id IN (?,?,?) AND status=?)", cond)
+	ass.Equal([]interface{}{3, 10, 20, 2, "b", 10, "a", 2, 3, 10, 1}, vals)
+
+	_, _, err = BuildBulkUpdate("tb", "id", nil, nil)
+	ass.Equal(errBulkUpdateEmpty, err)
+	_, _, err = BuildBulkUpdate("tb", "id", map[interface{}]map[string]interface{}{
+		1: {"id": 2},
+	}, nil)
+	ass.Equal(errBulkUpdateKey, err)
+
+	cond, vals, err = BuildBulkUpdate("tb", "id", map[interface{}]map[string]interface{}{
+		1: {"stock": Decr(1), "price": Expr("price*?", 1.1)},
+		2: {"stock": Incr(5), "price": Coalesce(9)},
+		3: {"stock": 0},
+	}, nil)
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET price=CASE id WHEN ? THEN price*? WHEN ? THEN COALESCE(price,?) ELSE price END,stock=CASE id WHEN ? THEN stock-? WHEN ? THEN stock+? WHEN ? THEN ? ELSE stock END WHERE (id IN (?,?,?))", cond)
+	ass.Equal([]interface{}{1, 1.1, 2, 9, 1, 1, 2, 5, 3, 0, 1, 2, 3}, vals)
+	_, _, err = BuildBulkUpdate("tb", "id", map[interface{}]map[string]interface{}{
+		1: {"name": Inserted("name")},
+	}, nil)
+	ass.Equal(errBulkUpdateInserted, err)
+}
+
+func TestBuildUpsert(t *testing.T) {
+	data := []map[string]interface{}{
+		{"id": 1, "name": "deen", "age": 23},
+		{"id": 2, "name": "Tony", "age": 30},
+	}
+	var testData = []struct {
+		dialect Dialect
+		keys    []string
+		cond    string
+		err     error
+	}{
+		{
+			dialect: MySQL,
+			keys:    []string{"id"},
+			cond:    "INSERT INTO tb (age,id,name) VALUES (?,?,?),(?,?,?) ON DUPLICATE KEY UPDATE age=VALUES(age),name=VALUES(name)",
+		},
+		{
+			dialect: MySQL8,
+			keys:    []string{"id"},
+			cond:    "INSERT INTO tb (age,id,name) VALUES (?,?,?),(?,?,?) AS new ON DUPLICATE KEY UPDATE age=new.age,name=new.name",
+		},
+		{
+			dialect: MySQL8,
+			keys:    []string{"id", "name"},
+			cond:    "INSERT INTO tb (age,id,name) VALUES (?,?,?),(?,?,?) AS new ON DUPLICATE KEY UPDATE age=new.age",
+		},
+		{
+			dialect: MySQL8,
+			keys:    []string{"id", "name", "age"},
+			err:     errUpsertNoColumn,
+		},
+	}
+	ass := assert.New(t)
+	defer SetDialect(MySQL)
+	for _, tc := range testData {
+		SetDialect(tc.dialect)
+		cond, vals, err := BuildUpsert("tb", data, tc.keys...)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		if nil == tc.err {
+			ass.Equal([]interface{}{23, 1, "deen", 30, 2, "Tony"}, vals)
+		}
+	}
+
+	SetDialect(MySQL8)
+	cond, vals, err := BuildInsertOnDuplicate("tb", data[:1], map[string]interface{}{
+		"name": Inserted("name"),
+		"age":  Incr(1),
+	})
+	ass.NoError(err)
+	ass.Equal("INSERT INTO tb (age,id,name) VALUES (?,?,?) AS new ON DUPLICATE KEY UPDATE age=age+?,name=new.name", cond)
+	ass.Equal([]interface{}{23, 1, "deen", 1}, vals)
+	// no alias is added if the inserted row is not referred
+	cond, _, err = BuildInsertOnDuplicate("tb", data[:1], map[string]interface{}{"age": Incr(1)})
+	ass.NoError(err)
+	ass.Equal("INSERT INTO tb (age,id,name) VALUES (?,?,?) ON DUPLICATE KEY UPDATE age=age+?", cond)
+}
+
+func TestBuildLockModeDialect(t *testing.T) {
+	var data = []struct {
+		dialect  Dialect
+		lockMode string
+		cond     string
+		err      error
+	}{
+		{dialect: MySQL, lockMode: "exclusive", cond: "SELECT * FROM tb WHERE (id=?) FOR UPDATE"},
+		{dialect: MySQL, lockMode: "share", cond: "SELECT * FROM tb WHERE (id=?) LOCK IN SHARE MODE"},
+		{dialect: MySQL, lockMode: "exclusive nowait", err: errors.New(`[builder] "nowait" of "_lockMode" is not supported by dialect mysql`)},
+		{dialect: MySQL, lockMode: "exclusive of tb", err: errors.New(`[builder] "of" of "_lockMode" is not supported by dialect mysql`)},
+		{dialect: MySQL8, lockMode: "share", cond: "SELECT * FROM tb WHERE (id=?) FOR SHARE"},
+		{dialect: MySQL8, lockMode: "exclusive  SKIP  LOCKED", cond: "SELECT * FROM tb WHERE (id=?) FOR UPDATE SKIP LOCKED"},
+		{dialect: MySQL8, lockMode: "exclusive nowait", cond: "SELECT * FROM tb WHERE (id=?) FOR UPDATE NOWAIT"},
+		{dialect: MySQL8, lockMode: "share of tb, t2 nowait", cond: "SELECT * FROM tb WHERE (id=?) FOR SHARE OF tb,t2 NOWAIT"},
+		{dialect: MySQL8, lockMode: "exclusive of tb", cond: "SELECT * FROM tb WHERE (id=?) FOR UPDATE OF tb"},
+		{dialect: MySQL8, lockMode: "exclusive of", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of t1;drop table x", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of t1 t2", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of t1,,t2", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of t1, skip locked", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of `t1`", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "exclusive of t1 ,t_2 skip locked", cond: "SELECT * FROM tb WHERE (id=?) FOR UPDATE OF t1,t_2 SKIP LOCKED"},
+		{dialect: MySQL8, lockMode: "exclusive wait", err: errNotAllowedLockMode},
+		{dialect: MySQL8, lockMode: "update", err: errNotAllowedLockMode},
+		{dialect: MariaDB, lockMode: "share skip locked", cond: "SELECT * FROM tb WHERE (id=?) LOCK IN SHARE MODE SKIP LOCKED"},
+	}
+	ass := assert.New(t)
+	defer SetDialect(MySQL)
+	for _, tc := range data {
+		SetDialect(tc.dialect)
+		cond, _, err := BuildSelect("tb", map[string]interface{}{"id": 1, "_lockMode": tc.lockMode}, nil)
+		ass.Equal(tc.err, err, tc.lockMode)
+		ass.Equal(tc.cond, cond, tc.lockMode)
+	}
+}
+
+func TestBuildSelectExpr(t *testing.T) {
+	ass := assert.New(t)
+	m := Match("+mysql -oracle", BooleanMode, "title", "body")
+	cond, vals, err := BuildSelectExpr("article", map[string]interface{}{
+		"_custom_0": m,
+		"status":    1,
+		"_orderby":  "score DESC",
+		"_limit":    []uint{10},
+	}, []string{"id"}, m.Score("score"), SelectExpr{Expr: "IF(level > ?, 'vip', '?') AS tag", Args: []interface{}{3}})
+	ass.NoError(err)
+	ass.Equal("SELECT id,MATCH(title,body) AGAINST (? IN BOOLEAN MODE) AS score,IF(level > ?, 'vip', '?') AS tag FROM article WHERE (MATCH(title,body) AGAINST (? IN BOOLEAN MODE) AND status=?) ORDER BY score DESC LIMIT ?,?", cond)
+	ass.Equal([]interface{}{"+mysql -oracle", 3, "+mysql -oracle", 1, 0, 10}, vals)
+
+	_, _, err = BuildSelectExpr("article", nil, nil, SelectExpr{Expr: "MATCH(title) AGAINST (?)"})
+	ass.Equal(errors.New(`[builder] select expression "MATCH(title) AGAINST (?)" has 1 placeholders but 0 args`), err)
+}
+
+func TestBuildSelectModifier(t *testing.T) {
+	var data = []struct {
+		where map[string]interface{}
+		cond  string
+		err   error
+	}{
+		{
+			where: map[string]interface{}{"a": 1, "_distinct": true},
+			cond:  "SELECT DISTINCT name FROM tb WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_distinct": false},
+			cond:  "SELECT name FROM tb WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{
+				"a":         1,
+				"_distinct": true,
+				"_modifier": []string{"sql_calc_found_rows", "SQL_NO_CACHE", "STRAIGHT_JOIN", "HIGH_PRIORITY"},
+				"_hint":     "MAX_EXECUTION_TIME(1000)",
+			},
+			cond: "SELECT /*+ MAX_EXECUTION_TIME(1000) */ DISTINCT HIGH_PRIORITY STRAIGHT_JOIN SQL_NO_CACHE SQL_CALC_FOUND_ROWS name FROM tb WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_modifier": "SQL_BUFFER_RESULT  SQL_SMALL_RESULT"},
+			cond:  "SELECT SQL_SMALL_RESULT SQL_BUFFER_RESULT name FROM tb WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_distinct": "yes"},
+			err:   errDistinctValueType,
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_modifier": 1},
+			err:   errModifierValueType,
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_modifier": "SQL_CACHE"},
+			err:   errors.New(`[builder] "SQL_CACHE" is not a supported select modifier`),
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_modifier": []string{"SQL_SMALL_RESULT", "SQL_BIG_RESULT"}},
+			err:   errModifierConflict,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, _, err := BuildSelect("tb", tc.where, []string{"name"})
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+	}
+}
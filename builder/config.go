@@ -0,0 +1,57 @@
+package builder
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// config holds the process-wide settings changed by SetDialect, SetInListLimit, SetNilAsNull,
+// SetVersionColumn, SetRedactedColumns, RegisterScope, SetRowEstimate and SetMaxRowsWithoutLimit.
+// A stored config is never modified, the setters store a modified copy instead,
+// so builders read it without locking and never see a half-applied change
+type config struct {
+	dialect Dialect
+
+	inListThreshold int
+	inListStrategy  InListStrategy
+
+	nilAsNull bool
+
+	// versionColumns is keyed by table
+	versionColumns map[string]string
+
+	// redactedColumns is keyed by lower-cased column
+	redactedColumns map[string]struct{}
+
+	// scopes is keyed by table
+	scopes map[string]Scope
+
+	// rowEstimates is keyed by table
+	rowEstimates        map[string]int64
+	maxRowsWithoutLimit int64
+}
+
+var (
+	// configLock serializes the setters, readers don't need it
+	configLock    sync.Mutex
+	currentConfig atomic.Value
+)
+
+func init() {
+	currentConfig.Store(&config{dialect: MySQL})
+}
+
+// loadConfig returns the current settings, which must not be modified
+func loadConfig() *config {
+	return currentConfig.Load().(*config)
+}
+
+// updateConfig applies update to a copy of the current settings and stores it,
+// update must replace the maps it changes rather than write into them
+func updateConfig(update func(c *config)) {
+	configLock.Lock()
+	defer configLock.Unlock()
+	c := *loadConfig()
+	update(&c)
+	currentConfig.Store(&c)
+}
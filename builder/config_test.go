@@ -0,0 +1,49 @@
+package builder
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigConcurrency(t *testing.T) {
+	defer SetDialect(MySQL)
+	defer RemoveScope("cc")
+	ass := assert.New(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if 0 == (i+j)%2 {
+					SetDialect(MySQL8)
+					RegisterScope("cc", Scope{Where: map[string]interface{}{"deleted_at": IsNull}})
+				} else {
+					SetDialect(MySQL)
+					RemoveScope("cc")
+				}
+				SetRowEstimate("cc", int64(j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _, err := BuildSelect("cc", map[string]interface{}{"id": 1, "_lockMode": "share"}, nil)
+				ass.NoError(err)
+				_, _, err = BuildSafeDelete("cc", map[string]interface{}{"id": 1})
+				ass.NoError(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the registered scope is a copy
+	where := map[string]interface{}{"deleted_at": IsNull}
+	RegisterScope("cc", Scope{Where: where})
+	where["tenant_id"] = 1
+	cond, _, err := BuildSelect("cc", map[string]interface{}{"id": 1}, nil)
+	ass.NoError(err)
+	ass.Equal("SELECT * FROM cc WHERE (id=? AND deleted_at IS NULL)", cond)
+}
@@ -1,16 +1,33 @@
 package builder
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 var (
 	errInsertDataNotMatch = errors.New("insert data not match")
 	errInsertNullData     = errors.New("insert null data")
 	errOrderByParam       = errors.New("order param only should be ASC or DESC")
+	errUpsertNoColumn     = errors.New("[builder] upsert has no column to update except the keys")
+	errBulkUpdateEmpty    = errors.New("[builder] bulk update requires at least one row and one column")
+	errBulkUpdateKey      = errors.New("[builder] bulk update can't update the key column")
+	errBulkUpdateInserted = errors.New("[builder] Inserted can only be used in the update of an insert")
+	errTupleColumns       = errors.New("[builder] tuple in requires at least one column")
+	errTupleValues        = errors.New("[builder] tuple in requires at least one tuple")
+
+	errTupleArity  = "[builder] tuple in requires %d values per tuple but got %d"
+	errTupleColumn = `[builder] "%s" is not a valid column of tuple in`
+
+	errModifierConflict = errors.New("[builder] SQL_SMALL_RESULT and SQL_BIG_RESULT can't be used together")
+
+	errModifierName    = `[builder] "%s" is not a supported select modifier`
+	errLockModeDialect = `[builder] "%s" of "_lockMode" is not supported by dialect %s`
 
 	allowedLockMode = map[string]string{
 		"share":     " LOCK IN SHARE MODE",
@@ -18,6 +35,60 @@ var (
 	}
 )
 
+// resolveLockMode translates the value of _lockMode into the locking clause,
+// which is share or exclusive optionally followed by "of t1,t2" and "nowait" or "skip locked"
+func resolveLockMode(lockMode string) (string, error) {
+	tokens := strings.Fields(lockMode)
+	if 0 == len(tokens) {
+		return "", errNotAllowedLockMode
+	}
+	mode := strings.ToLower(tokens[0])
+	if _, ok := allowedLockMode[mode]; !ok {
+		return "", errNotAllowedLockMode
+	}
+	tokens = tokens[1:]
+	dialect := loadConfig().dialect
+	var of, wait string
+	if len(tokens) > 0 && strings.EqualFold(tokens[0], "of") {
+		i := 1
+		for i < len(tokens) && !strings.EqualFold(tokens[i], "nowait") && !strings.EqualFold(tokens[i], "skip") {
+			i++
+		}
+		tables := strings.Split(strings.Join(tokens[1:i], " "), ",")
+		for j, table := range tables {
+			tables[j] = strings.TrimSpace(table)
+			if !isIdentifier(tables[j]) {
+				return "", errNotAllowedLockMode
+			}
+		}
+		of = strings.Join(tables, ",")
+		if !dialect.LockOf {
+			return "", fmt.Errorf(errLockModeDialect, "of", dialect.Name)
+		}
+		tokens = tokens[i:]
+	}
+	switch strings.ToLower(strings.Join(tokens, " ")) {
+	case "":
+	case "nowait":
+		wait = " NOWAIT"
+	case "skip locked":
+		wait = " SKIP LOCKED"
+	default:
+		return "", errNotAllowedLockMode
+	}
+	if "" != wait && !dialect.LockNoWait {
+		return "", fmt.Errorf(errLockModeDialect, strings.ToLower(strings.TrimSpace(wait)), dialect.Name)
+	}
+	clause := allowedLockMode[mode]
+	if "share" == mode && dialect.LockOf {
+		clause = " FOR SHARE"
+	}
+	if "" != of {
+		clause += " OF " + of
+	}
+	return clause + wait, nil
+}
+
 // the order of a map is unpredicatable so we need a sort algorithm to sort the fields
 // and make it predicatable
 var (
@@ -131,6 +202,14 @@ func (e Eq) Build() ([]string, []interface{}) {
 	return build(e, "=")
 }
 
+// NullSafeEq means NULL-safe equal(<=>), it's true when both sides are NULL
+type NullSafeEq map[string]interface{}
+
+// Build implements the Comparable interface
+func (e NullSafeEq) Build() ([]string, []interface{}) {
+	return build(e, "<=>")
+}
+
 // Ne means Not Equal(!=)
 type Ne map[string]interface{}
 
@@ -186,13 +265,51 @@ func (i In) Build() ([]string, []interface{}) {
 	}
 	defaultSortAlgorithm(cond)
 	for j := 0; j < len(cond); j++ {
-		val := i[cond[j]]
-		cond[j] = buildIn(cond[j], val)
-		vals = append(vals, val...)
+		val, hasNil := removeNil(i[cond[j]])
+		if 0 == len(val) && hasNil {
+			cond[j] = quoteField(cond[j]) + " " + IsNull.String()
+			continue
+		}
+		field := cond[j]
+		if c, v, ok := buildLargeIn(field, val, "IN"); ok {
+			cond[j] = c
+			vals = append(vals, v...)
+		} else {
+			cond[j] = buildIn(field, val)
+			vals = append(vals, val...)
+		}
+		if hasNil {
+			cond[j] = "(" + cond[j] + " OR " + quoteField(field) + " " + IsNull.String() + ")"
+		}
 	}
 	return cond, vals
 }
 
+// removeNil takes the nil values, including typed nil pointers, out of an IN list if SetNilAsNull is on.
+// NULL never equals to anything, so it has to be checked separately by IS NULL
+func removeNil(vals []interface{}) ([]interface{}, bool) {
+	if !loadConfig().nilAsNull {
+		return vals, false
+	}
+	var hasNil bool
+	for _, v := range vals {
+		if isNilValue(v) {
+			hasNil = true
+			break
+		}
+	}
+	if !hasNil {
+		return vals, false
+	}
+	notNil := make([]interface{}, 0, len(vals))
+	for _, v := range vals {
+		if !isNilValue(v) {
+			notNil = append(notNil, v)
+		}
+	}
+	return notNil, true
+}
+
 func buildIn(field string, vals []interface{}) (cond string) {
 	cond = strings.TrimRight(strings.Repeat("?,", len(vals)), ",")
 	cond = fmt.Sprintf("%s IN (%s)", quoteField(field), cond)
@@ -214,9 +331,22 @@ func (i NotIn) Build() ([]string, []interface{}) {
 	}
 	defaultSortAlgorithm(cond)
 	for j := 0; j < len(cond); j++ {
-		val := i[cond[j]]
-		cond[j] = buildNotIn(cond[j], val)
-		vals = append(vals, val...)
+		val, hasNil := removeNil(i[cond[j]])
+		if 0 == len(val) && hasNil {
+			cond[j] = quoteField(cond[j]) + " " + IsNotNull.String()
+			continue
+		}
+		field := cond[j]
+		if c, v, ok := buildLargeIn(field, val, "NOT IN"); ok {
+			cond[j] = c
+			vals = append(vals, v...)
+		} else {
+			cond[j] = buildNotIn(field, val)
+			vals = append(vals, val...)
+		}
+		if hasNil {
+			cond[j] = "(" + cond[j] + " AND " + quoteField(field) + " " + IsNotNull.String() + ")"
+		}
 	}
 	return cond, vals
 }
@@ -227,6 +357,161 @@ func buildNotIn(field string, vals []interface{}) (cond string) {
 	return
 }
 
+// InListStrategy decides how an IN list longer than the threshold of SetInListLimit is built
+type InListStrategy uint8
+
+const (
+	// InListExpand expands every element into a placeholder, which is the default
+	InListExpand InListStrategy = iota
+	// InListJSONTable binds the whole list as one JSON array and reads it back through JSON_TABLE,
+	// it requires MySQL 8.0.4+. Lists of mixed or non-scalar types are still expanded
+	InListJSONTable
+	// InListChunk splits the query into several ones, each taking a part of the list,
+	// it only takes effect in BuildSelectChunks and QueryInChunks
+	InListChunk
+)
+
+// SetInListLimit sets how IN lists containing more than threshold elements are built,
+// threshold <= 0 disables it. The threshold and strategy are replaced together,
+// so concurrent builders see either the old pair or the new one
+func SetInListLimit(threshold int, strategy InListStrategy) {
+	updateConfig(func(c *config) {
+		c.inListThreshold, c.inListStrategy = threshold, strategy
+	})
+}
+
+func (c *config) isLargeInList(length int) bool {
+	return c.inListThreshold > 0 && length > c.inListThreshold
+}
+
+// buildLargeIn rewrites a long IN list into `field IN (SELECT ... FROM JSON_TABLE(?, ...))` if configured
+func buildLargeIn(field string, vals []interface{}, op string) (string, []interface{}, bool) {
+	if c := loadConfig(); c.inListStrategy != InListJSONTable || !c.isLargeInList(len(vals)) {
+		return "", nil, false
+	}
+	colType, ok := jsonTableColumnType(vals)
+	if !ok {
+		return "", nil, false
+	}
+	b, err := json.Marshal(vals)
+	if nil != err {
+		return "", nil, false
+	}
+	cond := fmt.Sprintf("%s %s (SELECT jt.v FROM JSON_TABLE(?,'$[*]' COLUMNS(v %s PATH '$')) AS jt)", quoteField(field), op, colType)
+	return cond, []interface{}{string(b)}, true
+}
+
+// jsonTableColumnType returns the column type JSON_TABLE should use for vals,
+// it fails if vals contains different kinds of values
+func jsonTableColumnType(vals []interface{}) (string, bool) {
+	var colType string
+	maxLen := 1
+	for _, val := range vals {
+		var t string
+		switch v := val.(type) {
+		case int, int8, int16, int32, int64:
+			t = "BIGINT"
+		case uint, uint8, uint16, uint32, uint64:
+			t = "BIGINT UNSIGNED"
+		case float32, float64:
+			t = "DOUBLE"
+		case string:
+			t = "VARCHAR"
+			if n := utf8.RuneCountInString(v); n > maxLen {
+				maxLen = n
+			}
+		default:
+			return "", false
+		}
+		if "" != colType && t != colType {
+			return "", false
+		}
+		colType = t
+	}
+	if "VARCHAR" == colType {
+		colType = fmt.Sprintf("VARCHAR(%d)", maxLen)
+	}
+	return colType, "" != colType
+}
+
+type tupleIn struct {
+	columns []string
+	values  [][]interface{}
+	not     bool
+}
+
+// InTuple means (col1,col2) IN ((?,?),(?,?)), every tuple must contain one value per column.
+// if the dialect doesn't support row values it is expanded into ((col1=? AND col2=?) OR (col1=? AND col2=?))
+func InTuple(columns []string, values ...[]interface{}) (Comparable, error) {
+	return newTupleIn(columns, values, false)
+}
+
+// NotInTuple means (col1,col2) NOT IN ((?,?),(?,?)), see InTuple
+func NotInTuple(columns []string, values ...[]interface{}) (Comparable, error) {
+	return newTupleIn(columns, values, true)
+}
+
+func newTupleIn(columns []string, values [][]interface{}, not bool) (Comparable, error) {
+	if 0 == len(columns) {
+		return nil, errTupleColumns
+	}
+	trimmed := make([]string, len(columns))
+	for i, col := range columns {
+		trimmed[i] = strings.TrimSpace(col)
+		if !isColumnName(trimmed[i]) {
+			return nil, fmt.Errorf(errTupleColumn, col)
+		}
+	}
+	columns = trimmed
+	if 0 == len(values) {
+		return nil, errTupleValues
+	}
+	for _, tuple := range values {
+		if len(tuple) != len(columns) {
+			return nil, fmt.Errorf(errTupleArity, len(columns), len(tuple))
+		}
+	}
+	return tupleIn{columns: columns, values: values, not: not}, nil
+}
+
+// Build implements the Comparable interface
+func (t tupleIn) Build() ([]string, []interface{}) {
+	if 0 == len(t.values) {
+		return nil, nil
+	}
+	vals := make([]interface{}, 0, len(t.columns)*len(t.values))
+	for _, tuple := range t.values {
+		vals = append(vals, tuple...)
+	}
+	if !loadConfig().dialect.RowValue {
+		return []string{t.buildOrChain()}, vals
+	}
+	fields := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		fields[i] = quoteField(col)
+	}
+	placeholder := "(" + strings.TrimRight(strings.Repeat("?,", len(t.columns)), ",") + ")"
+	op := " IN "
+	if t.not {
+		op = " NOT IN "
+	}
+	cond := "(" + strings.Join(fields, ",") + ")" + op + "(" + strings.TrimRight(strings.Repeat(placeholder+",", len(t.values)), ",") + ")"
+	return []string{cond}, vals
+}
+
+func (t tupleIn) buildOrChain() string {
+	eqs := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		eqs[i] = assembleExpression(col, "=")
+	}
+	tuple := "(" + strings.Join(eqs, " AND ") + ")"
+	cond := "(" + strings.TrimSuffix(strings.Repeat(tuple+" OR ", len(t.values)), " OR ") + ")"
+	if t.not {
+		cond = "NOT " + cond
+	}
+	return cond
+}
+
 type Between map[string][]interface{}
 
 func (bt Between) Build() ([]string, []interface{}) {
@@ -274,12 +559,103 @@ func buildBetween(notBetween bool, key string, vals []interface{}) (string, erro
 	return fmt.Sprintf("(%s %s ? AND ?)", key, operator), nil
 }
 
+// FullTextMode is the search modifier of MATCH ... AGAINST
+type FullTextMode uint8
+
+const (
+	// NaturalLanguageMode is the default mode of full-text search
+	NaturalLanguageMode FullTextMode = iota
+	// BooleanMode the same as `IN BOOLEAN MODE`
+	BooleanMode
+	// QueryExpansionMode the same as `WITH QUERY EXPANSION`
+	QueryExpansionMode
+)
+
+func (m FullTextMode) modifier() string {
+	switch m {
+	case BooleanMode:
+		return " IN BOOLEAN MODE"
+	case QueryExpansionMode:
+		return " WITH QUERY EXPANSION"
+	}
+	return ""
+}
+
+// MatchAgainst means MATCH(col1,col2) AGAINST (? mode)
+// the columns must be exactly the ones covered by a FULLTEXT index
+type MatchAgainst struct {
+	Columns []string
+	Query   interface{}
+	Mode    FullTextMode
+}
+
+// Match is a helper creating a MatchAgainst
+// usage where := map[string]interface{}{"_custom_xxx": builder.Match("+mysql -oracle", builder.BooleanMode, "title", "body")}
+func Match(query interface{}, mode FullTextMode, columns ...string) MatchAgainst {
+	return MatchAgainst{Columns: columns, Query: query, Mode: mode}
+}
+
+// Build implements the Comparable interface
+func (m MatchAgainst) Build() ([]string, []interface{}) {
+	if 0 == len(m.Columns) {
+		return nil, nil
+	}
+	return []string{m.expression()}, []interface{}{m.Query}
+}
+
+// Score returns the relevance expression used as a select field of BuildSelectExpr,
+// e.g. `MATCH(title) AGAINST (?) AS score` with the query as its arg
+func (m MatchAgainst) Score(alias string) SelectExpr {
+	field := m.expression()
+	if "" != alias {
+		field += " AS " + alias
+	}
+	return SelectExpr{Expr: field, Args: []interface{}{m.Query}}
+}
+
+func (m MatchAgainst) expression() string {
+	fields := make([]string, len(m.Columns))
+	for i, col := range m.Columns {
+		fields[i] = quoteField(strings.TrimSpace(col))
+	}
+	return "MATCH(" + strings.Join(fields, ",") + ") AGAINST (?" + m.Mode.modifier() + ")"
+}
+
+type fullTextMap struct {
+	mode FullTextMode
+	m    map[string]interface{}
+}
+
+// Build implements the Comparable interface
+// every key is a comma separated column list, e.g. "title,body"
+func (f fullTextMap) Build() ([]string, []interface{}) {
+	if 0 == len(f.m) {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(f.m))
+	for k := range f.m {
+		keys = append(keys, k)
+	}
+	defaultSortAlgorithm(keys)
+	cond := make([]string, 0, len(keys))
+	vals := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		c, v := Match(f.m[k], f.mode, strings.Split(k, ",")...).Build()
+		cond = append(cond, c...)
+		vals = append(vals, v...)
+	}
+	return cond, vals
+}
+
 type NestWhere []Comparable
 
 func (nw NestWhere) Build() ([]string, []interface{}) {
 	var cond []string
 	var vals []interface{}
 	nestWhereString, nestWhereVals := whereConnector("AND", nw...)
+	if "" == nestWhereString {
+		return nil, nil
+	}
 	cond = append(cond, nestWhereString)
 	vals = nestWhereVals
 	return cond, vals
@@ -291,6 +667,9 @@ func (ow OrWhere) Build() ([]string, []interface{}) {
 	var cond []string
 	var vals []interface{}
 	orWhereString, orWhereVals := whereConnector("OR", ow...)
+	if "" == orWhereString {
+		return nil, nil
+	}
 	cond = append(cond, orWhereString)
 	vals = orWhereVals
 	return cond, vals
@@ -391,11 +770,17 @@ func buildInsert(table string, setMap []map[string]interface{}, insertType inser
 	return fmt.Sprintf(format, insertType, quoteField(table), strings.Join(fields, ","), strings.Join(sets, ",")), vals, nil
 }
 
+// insertRowAlias is the alias of the inserted row when the dialect supports RowAlias
+const insertRowAlias = "new"
+
 func buildInsertOnDuplicate(table string, data []map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
 	insertCond, insertVals, err := buildInsert(table, data, commonInsert)
 	if err != nil {
 		return "", nil, err
 	}
+	if loadConfig().dialect.RowAlias && referInsertedRow(update) {
+		insertCond += " AS " + insertRowAlias
+	}
 	sets, updateVals := resolveUpdate(update)
 	format := "%s ON DUPLICATE KEY UPDATE %s"
 	cond := fmt.Sprintf(format, insertCond, sets)
@@ -403,6 +788,31 @@ func buildInsertOnDuplicate(table string, data []map[string]interface{}, update
 	return cond, vals, nil
 }
 
+func referInsertedRow(update map[string]interface{}) bool {
+	for _, v := range update {
+		if expr, ok := v.(UpdateExpr); ok && expr.inserted {
+			return true
+		}
+	}
+	return false
+}
+
+func buildUpsert(table string, data []map[string]interface{}, keys []string) (string, []interface{}, error) {
+	if len(data) < 1 {
+		return "", nil, errInsertNullData
+	}
+	update := make(map[string]interface{})
+	for _, field := range resolveFields(data[0]) {
+		if !isStringInSlice(field, keys) {
+			update[field] = Inserted(field)
+		}
+	}
+	if 0 == len(update) {
+		return "", nil, errUpsertNoColumn
+	}
+	return buildInsertOnDuplicate(table, data, update)
+}
+
 func resolveUpdate(update map[string]interface{}) (sets string, vals []interface{}) {
 	keys := make([]string, 0, len(update))
 	for key := range update {
@@ -416,6 +826,13 @@ func resolveUpdate(update map[string]interface{}) (sets string, vals []interface
 			sb.WriteString(fmt.Sprintf("%s=%s,", k, v))
 			continue
 		}
+		if expr, ok := v.(UpdateExpr); ok {
+			set, val := expr.build(quoteField(k))
+			sb.WriteString(set)
+			sb.WriteByte(',')
+			vals = append(vals, val...)
+			continue
+		}
 		if strings.HasPrefix(k, "_custom_") {
 			if custom, ok := v.(Comparable); ok {
 				sql, val := custom.Build()
@@ -434,6 +851,102 @@ func resolveUpdate(update map[string]interface{}) (sets string, vals []interface
 	return sets, vals
 }
 
+func buildBulkUpdate(table, key string, rows map[interface{}]map[string]interface{}, conditions ...Comparable) (string, []interface{}, error) {
+	if 0 == len(rows) {
+		return "", nil, errBulkUpdateEmpty
+	}
+	ids := make([]interface{}, 0, len(rows))
+	columnSet := make(map[string]struct{})
+	for id, row := range rows {
+		ids = append(ids, id)
+		for col, v := range row {
+			if col == key {
+				return "", nil, errBulkUpdateKey
+			}
+			if expr, ok := v.(UpdateExpr); ok && expr.inserted {
+				return "", nil, errBulkUpdateInserted
+			}
+			columnSet[col] = struct{}{}
+		}
+	}
+	if 0 == len(columnSet) {
+		return "", nil, errBulkUpdateEmpty
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessValue(ids[i], ids[j])
+	})
+	columns := make([]string, 0, len(columnSet))
+	for col := range columnSet {
+		columns = append(columns, col)
+	}
+	defaultSortAlgorithm(columns)
+	var sb strings.Builder
+	var vals []interface{}
+	sb.WriteString("UPDATE ")
+	sb.WriteString(quoteField(table))
+	sb.WriteString(" SET ")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(quoteField(col))
+		sb.WriteString("=CASE ")
+		sb.WriteString(quoteField(key))
+		for _, id := range ids {
+			v, ok := rows[id][col]
+			if !ok {
+				continue
+			}
+			sb.WriteString(" WHEN ? THEN ")
+			vals = append(vals, id)
+			switch x := v.(type) {
+			case Raw:
+				sb.WriteString(string(x))
+				continue
+			case UpdateExpr:
+				value, args := x.value(quoteField(col))
+				sb.WriteString(value)
+				vals = append(vals, args...)
+				continue
+			}
+			sb.WriteString(paramPlaceHolder)
+			vals = append(vals, v)
+		}
+		// rows without this column keep their value
+		sb.WriteString(" ELSE ")
+		sb.WriteString(quoteField(col))
+		sb.WriteString(" END")
+	}
+	conditions = append([]Comparable{In{key: ids}}, conditions...)
+	whereString, whereVals := whereConnector("AND", conditions...)
+	sb.WriteString(" WHERE ")
+	sb.WriteString(whereString)
+	vals = append(vals, whereVals...)
+	return sb.String(), vals, nil
+}
+
+// lessValue orders values of the same kind naturally, others by their string form
+func lessValue(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isIntSeriesKind(va.Kind()) && isIntSeriesKind(vb.Kind()):
+		return va.Int() < vb.Int()
+	case isUintSeriesKind(va.Kind()) && isUintSeriesKind(vb.Kind()):
+		return va.Uint() < vb.Uint()
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return va.String() < vb.String()
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+func isIntSeriesKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintSeriesKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
 func buildUpdate(table string, update map[string]interface{}, limit uint, conditions ...Comparable) (string, []interface{}, error) {
 	format := "UPDATE %s SET %s"
 	sets, vals := resolveUpdate(update)
@@ -482,7 +995,76 @@ func splitCondition(conditions []Comparable) ([]Comparable, []Comparable) {
 	return conditions, nil
 }
 
-func buildSelect(table string, ufields []string, groupBy, orderBy, lockMode string, limit *eleLimit, conditions ...Comparable) (string, []interface{}, error) {
+// selectExtra holds the optional parts of a select which are rarely used
+type selectExtra struct {
+	// optimizerHint is placed as SELECT /*+ optimizerHint */
+	optimizerHint string
+	// modifiers are placed before the fields, DISTINCT included
+	modifiers string
+	// indexHint is placed after the table
+	indexHint string
+}
+
+// selectModifiers are the select modifiers of MySQL in grammar order
+var selectModifiers = []string{
+	"DISTINCT",
+	"HIGH_PRIORITY",
+	"STRAIGHT_JOIN",
+	"SQL_SMALL_RESULT",
+	"SQL_BIG_RESULT",
+	"SQL_BUFFER_RESULT",
+	"SQL_NO_CACHE",
+	"SQL_CALC_FOUND_ROWS",
+}
+
+// resolveSelectModifier validates the values of _distinct and _modifier
+// and joins the modifiers in grammar order
+func resolveSelectModifier(distinct, modifier interface{}) (string, error) {
+	set := make(map[string]struct{})
+	if nil != distinct {
+		on, ok := distinct.(bool)
+		if !ok {
+			return "", errDistinctValueType
+		}
+		if on {
+			set["DISTINCT"] = struct{}{}
+		}
+	}
+	var modifiers []string
+	switch v := modifier.(type) {
+	case nil:
+	case string:
+		modifiers = strings.Fields(v)
+	case []string:
+		modifiers = v
+	default:
+		return "", errModifierValueType
+	}
+	for _, m := range modifiers {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if "" == m {
+			continue
+		}
+		if !isStringInSlice(m, selectModifiers) {
+			return "", fmt.Errorf(errModifierName, m)
+		}
+		set[m] = struct{}{}
+	}
+	if _, small := set["SQL_SMALL_RESULT"]; small {
+		if _, big := set["SQL_BIG_RESULT"]; big {
+			return "", errModifierConflict
+		}
+	}
+	var parts []string
+	for _, m := range selectModifiers {
+		if _, ok := set[m]; ok {
+			parts = append(parts, m)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func buildSelect(table string, ufields []string, extra selectExtra, groupBy, orderBy, lockMode string, limit *eleLimit, conditions ...Comparable) (string, []interface{}, error) {
 	fields := "*"
 	if len(ufields) > 0 {
 		for i := range ufields {
@@ -492,9 +1074,22 @@ func buildSelect(table string, ufields []string, groupBy, orderBy, lockMode stri
 	}
 	bd := strings.Builder{}
 	bd.WriteString("SELECT ")
+	if "" != extra.optimizerHint {
+		bd.WriteString("/*+ ")
+		bd.WriteString(extra.optimizerHint)
+		bd.WriteString(" */ ")
+	}
+	if "" != extra.modifiers {
+		bd.WriteString(extra.modifiers)
+		bd.WriteByte(' ')
+	}
 	bd.WriteString(fields)
 	bd.WriteString(" FROM ")
 	bd.WriteString(table)
+	if "" != extra.indexHint {
+		bd.WriteByte(' ')
+		bd.WriteString(extra.indexHint)
+	}
 	where, having := splitCondition(conditions)
 	whereString, vals := whereConnector("AND", where...)
 	if "" != whereString {
@@ -520,7 +1115,11 @@ func buildSelect(table string, ufields []string, groupBy, orderBy, lockMode stri
 		vals = append(vals, int(limit.begin), int(limit.step))
 	}
 	if "" != lockMode {
-		bd.WriteString(allowedLockMode[lockMode])
+		clause, err := resolveLockMode(lockMode)
+		if nil != err {
+			return "", nil, err
+		}
+		bd.WriteString(clause)
 	}
 	return bd.String(), vals, nil
 }
@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -466,9 +467,123 @@ func TestBuildSelect(t *testing.T) {
 	}
 	ass := assert.New(t)
 	for _, tc := range data {
-		cond, vals, err := buildSelect(tc.table, tc.fields, tc.groupBy, tc.orderBy, tc.lockMode, tc.limit, tc.conditions...)
+		cond, vals, err := buildSelect(tc.table, tc.fields, selectExtra{}, tc.groupBy, tc.orderBy, tc.lockMode, tc.limit, tc.conditions...)
 		ass.Equal(tc.outErr, err)
 		ass.Equal(tc.outStr, cond)
 		ass.Equal(tc.outVals, vals)
 	}
 }
+
+func TestMatchAgainst(t *testing.T) {
+	var data = []struct {
+		in      Comparable
+		outCon  []string
+		outVals []interface{}
+	}{
+		{
+			in:      Match("database", NaturalLanguageMode, "title", "body"),
+			outCon:  []string{"MATCH(title,body) AGAINST (?)"},
+			outVals: []interface{}{"database"},
+		},
+		{
+			in:      Match("+mysql -oracle", BooleanMode, "title"),
+			outCon:  []string{"MATCH(title) AGAINST (? IN BOOLEAN MODE)"},
+			outVals: []interface{}{"+mysql -oracle"},
+		},
+		{
+			in:      Match("database", QueryExpansionMode, " title ", "body"),
+			outCon:  []string{"MATCH(title,body) AGAINST (? WITH QUERY EXPANSION)"},
+			outVals: []interface{}{"database"},
+		},
+		{
+			in:      Match("database", BooleanMode),
+			outCon:  nil,
+			outVals: nil,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		actualCond, actualVals := tc.in.Build()
+		ass.Equal(tc.outCon, actualCond)
+		ass.Equal(tc.outVals, actualVals)
+	}
+	score := Match("database", BooleanMode, "title", "body").Score("score")
+	ass.Equal(SelectExpr{Expr: "MATCH(title,body) AGAINST (? IN BOOLEAN MODE) AS score", Args: []interface{}{"database"}}, score)
+}
+
+func TestTupleIn(t *testing.T) {
+	ass := assert.New(t)
+	in, err := InTuple([]string{"tenant_id", "user_id"}, []interface{}{1, 2}, []interface{}{1, 3})
+	ass.NoError(err)
+	cond, vals := in.Build()
+	ass.Equal([]string{"(tenant_id,user_id) IN ((?,?),(?,?))"}, cond)
+	ass.Equal([]interface{}{1, 2, 1, 3}, vals)
+
+	notIn, err := NotInTuple([]string{"a", "b"}, []interface{}{1, 2})
+	ass.NoError(err)
+	cond, vals = notIn.Build()
+	ass.Equal([]string{"(a,b) NOT IN ((?,?))"}, cond)
+	ass.Equal([]interface{}{1, 2}, vals)
+
+	_, err = InTuple([]string{"a", "b"}, []interface{}{1, 2}, []interface{}{1})
+	ass.Equal(errors.New("[builder] tuple in requires 2 values per tuple but got 1"), err)
+	_, err = InTuple(nil, []interface{}{1})
+	ass.Equal(errTupleColumns, err)
+	trimmed, err := InTuple([]string{" t.a", "b "}, []interface{}{1, 2})
+	ass.NoError(err)
+	cond, _ = trimmed.Build()
+	ass.Equal([]string{"(t.a,b) IN ((?,?))"}, cond)
+	_, err = InTuple([]string{"a", ""}, []interface{}{1, 2})
+	ass.Equal(errors.New(`[builder] "" is not a valid column of tuple in`), err)
+	_, err = InTuple([]string{"a", "b;drop"}, []interface{}{1, 2})
+	ass.Equal(errors.New(`[builder] "b;drop" is not a valid column of tuple in`), err)
+	_, _, err = BuildSelect("tb", map[string]interface{}{"(a,) in": [][]interface{}{{1, 2}}}, nil)
+	ass.Equal(errors.New(`[builder] "" is not a valid column of tuple in`), err)
+	_, err = InTuple([]string{"a"})
+	ass.Equal(errTupleValues, err)
+
+	SetDialect(Dialect{Name: "norow"})
+	defer SetDialect(MySQL)
+	cond, vals = in.Build()
+	ass.Equal([]string{"((tenant_id=? AND user_id=?) OR (tenant_id=? AND user_id=?))"}, cond)
+	ass.Equal([]interface{}{1, 2, 1, 3}, vals)
+	cond, _ = notIn.Build()
+	ass.Equal([]string{"NOT ((a=? AND b=?))"}, cond)
+}
+
+func TestBuildLargeIn(t *testing.T) {
+	ass := assert.New(t)
+	SetInListLimit(2, InListJSONTable)
+	defer SetInListLimit(0, InListExpand)
+	var data = []struct {
+		in      Comparable
+		outCon  []string
+		outVals []interface{}
+	}{
+		{
+			in:      In{"id": {1, 2}},
+			outCon:  []string{"id IN (?,?)"},
+			outVals: []interface{}{1, 2},
+		},
+		{
+			in:      In{"id": {1, 2, int64(3)}},
+			outCon:  []string{"id IN (SELECT jt.v FROM JSON_TABLE(?,'$[*]' COLUMNS(v BIGINT PATH '$')) AS jt)"},
+			outVals: []interface{}{"[1,2,3]"},
+		},
+		{
+			in:      NotIn{"name": {"a", "中文", "abc"}},
+			outCon:  []string{"name NOT IN (SELECT jt.v FROM JSON_TABLE(?,'$[*]' COLUMNS(v VARCHAR(3) PATH '$')) AS jt)"},
+			outVals: []interface{}{`["a","中文","abc"]`},
+		},
+		{
+			in:      In{"id": {1, "2", 3}},
+			outCon:  []string{"id IN (?,?,?)"},
+			outVals: []interface{}{1, "2", 3},
+		},
+	}
+	for _, tc := range data {
+		actualCond, actualVals := tc.in.Build()
+		ass.Equal(tc.outCon, actualCond)
+		ass.Equal(tc.outVals, actualVals)
+	}
+}
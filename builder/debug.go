@@ -0,0 +1,295 @@
+package builder
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces the values of redacted columns
+const redactedValue = "'***'"
+
+// SetRedactedColumns replaces the columns whose values are hidden by InterpolateForLog,
+// columns are matched case-insensitively with or without the table qualifier.
+// A log line being formatted concurrently uses either the old columns or the new ones
+func SetRedactedColumns(columns ...string) {
+	redacted := make(map[string]struct{}, len(columns))
+	for _, col := range columns {
+		redacted[strings.ToLower(col)] = struct{}{}
+	}
+	updateConfig(func(c *config) {
+		c.redactedColumns = redacted
+	})
+}
+
+// logKeywords are the keywords which can't be the column a placeholder is compared with
+var logKeywords = map[string]struct{}{
+	"AND": {}, "OR": {}, "NOT": {}, "IN": {}, "IS": {}, "NULL": {}, "LIKE": {}, "BETWEEN": {},
+	"CASE": {}, "WHEN": {}, "THEN": {}, "ELSE": {}, "END": {}, "SET": {}, "WHERE": {}, "HAVING": {},
+	"SELECT": {}, "FROM": {}, "LIMIT": {}, "VALUES": {}, "AGAINST": {}, "INTERVAL": {},
+	"ON": {}, "AS": {}, "RETURNING": {}, "DUPLICATE": {}, "KEY": {}, "UPDATE": {},
+}
+
+// InterpolateForLog inlines vals into the placeholders of cond with MySQL escaping.
+// The result is for logging and debugging only, never execute it, use cond and vals instead.
+// Values compared with or assigned to the columns set by SetRedactedColumns are replaced by '***',
+// including the ones passed through a function call like password=SHA2(?,256).
+// Placeholders without a value are left as they are and extra values are ignored
+func InterpolateForLog(cond string, vals []interface{}) string {
+	var sb strings.Builder
+	sb.Grow(len(cond) + 8*len(vals))
+	redacted := loadConfig().redactedColumns
+	operatorWords := registeredOperatorWords()
+	var (
+		lastColumn, assignedColumn string
+		comparedColumn, callee     string
+		calleeColumn               string
+		parenColumns, columns      []string
+		// the column each open parenthesis belongs to, for the arguments of a function call
+		parenOwners            []string
+		collecting             bool
+		valuesDepth, columnIdx = -1, 0
+		depth, argIdx          int
+		prev                   string
+	)
+	// valueColumn is the column a value at the current position is compared with or assigned to
+	valueColumn := func() string {
+		if "THEN" == prev || "ELSE" == prev {
+			return assignedColumn
+		}
+		if valuesDepth >= 0 && depth == valuesDepth+1 && columnIdx < len(columns) {
+			return columns[columnIdx]
+		}
+		return comparedColumn
+	}
+	owner := func() string {
+		if 0 == len(parenOwners) {
+			return ""
+		}
+		return parenOwners[len(parenOwners)-1]
+	}
+	for _, tok := range tokenizeSQL(cond) {
+		text := tok.text
+		if !tok.significant {
+			sb.WriteString(text)
+			continue
+		}
+		switch upper := strings.ToUpper(text); {
+		case "?" == text:
+			column := lastColumn
+			if "THEN" == prev || "ELSE" == prev {
+				column = assignedColumn
+			}
+			if valuesDepth >= 0 && depth == valuesDepth+1 && columnIdx < len(columns) {
+				column = columns[columnIdx]
+			}
+			collecting, prev = false, text
+			switch {
+			case argIdx >= len(vals):
+				sb.WriteString(text)
+			case isRedactedColumn(redacted, column) || isRedactedColumn(redacted, owner()):
+				sb.WriteString(redactedValue)
+			default:
+				sb.WriteString(formatLogValue(vals[argIdx]))
+			}
+			argIdx++
+			continue
+		case "(" == text:
+			// the arguments of a function call belong to the column the call is compared with,
+			// the list or subquery after IN to the column before it,
+			// and other parentheses inherit the column of the enclosing one
+			switch {
+			case "IN" == prev && "" != lastColumn:
+				parenOwners = append(parenOwners, lastColumn)
+			case "" != callee && callee == prev && "" != calleeColumn:
+				parenOwners = append(parenOwners, calleeColumn)
+			default:
+				parenOwners = append(parenOwners, owner())
+			}
+			depth++
+			collecting, parenColumns = true, parenColumns[:0]
+		case ")" == text:
+			if collecting {
+				columns = append(columns[:0], parenColumns...)
+			}
+			collecting = false
+			if len(parenOwners) > 0 {
+				parenOwners = parenOwners[:len(parenOwners)-1]
+			}
+			depth--
+		case "," == text:
+			if valuesDepth >= 0 && depth == valuesDepth+1 {
+				columnIdx++
+			}
+			if 0 == depth {
+				comparedColumn = ""
+			}
+		case "=" == text || "<" == text || ">" == text:
+			if "" != lastColumn && !strings.ContainsAny(prev, "<>!=") {
+				comparedColumn = lastColumn
+				if "=" == text {
+					assignedColumn = lastColumn
+				}
+			}
+		case "VALUES" == upper:
+			// the columns of INSERT INTO tb (a,b) VALUES (?,?)
+			valuesDepth = depth
+		case isIdentStart(text[0]) || '`' == text[0]:
+			if _, ok := logKeywords[upper]; ok {
+				switch upper {
+				case "ON", "AS", "RETURNING":
+					valuesDepth = -1
+				case "LIKE":
+					comparedColumn = lastColumn
+				case "AND", "OR", "SET", "WHERE", "HAVING", "WHEN":
+					comparedColumn = ""
+				}
+				collecting = false
+				break
+			}
+			if _, ok := operatorWords[upper]; ok {
+				// registered operators like REGEXP and SOUNDS LIKE compare the column before them
+				comparedColumn, collecting = lastColumn, false
+				break
+			}
+			name := strings.Trim(text, "`")
+			callee = ""
+			if '`' != text[0] && "." != prev {
+				// maybe the name of a function, which is only known when ( follows
+				callee, calleeColumn = upper, valueColumn()
+			}
+			if "." == prev {
+				lastColumn += "." + name
+			} else {
+				lastColumn = name
+			}
+			if collecting {
+				parenColumns = append(parenColumns, name)
+			}
+		case "." == text:
+		default:
+			collecting = collecting && "." == text
+		}
+		if "(" == text && valuesDepth >= 0 && depth == valuesDepth+1 {
+			columnIdx = 0
+		}
+		sb.WriteString(text)
+		prev = strings.ToUpper(text)
+	}
+	return sb.String()
+}
+
+// registeredOperatorWords returns the words of registered operators, e.g. SOUNDS and LIKE of "sounds like"
+func registeredOperatorWords() map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, op := range registeredOperators() {
+		for _, word := range strings.Fields(op.Name) {
+			if isIdentifier(word) {
+				words[strings.ToUpper(word)] = struct{}{}
+			}
+		}
+	}
+	return words
+}
+
+func isRedactedColumn(redactedColumns map[string]struct{}, column string) bool {
+	if "" == column || 0 == len(redactedColumns) {
+		return false
+	}
+	column = strings.ToLower(column)
+	if _, ok := redactedColumns[column]; ok {
+		return true
+	}
+	if idx := strings.LastIndexByte(column, '.'); idx >= 0 {
+		_, ok := redactedColumns[column[idx+1:]]
+		return ok
+	}
+	return false
+}
+
+// formatLogValue formats v as a MySQL literal
+func formatLogValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL"
+		}
+		val, err := x.Value()
+		if nil != err {
+			return quoteLogString(fmt.Sprintf("<%v>", err))
+		}
+		return formatLogValue(val)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case string:
+		return quoteLogString(x)
+	case []byte:
+		if nil == x {
+			return "NULL"
+		}
+		return "X'" + hex.EncodeToString(x) + "'"
+	case time.Time:
+		if x.IsZero() {
+			return "'0000-00-00'"
+		}
+		return "'" + x.Format("2006-01-02 15:04:05.999999") + "'"
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return formatLogValue(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return formatLogValue(rv.Bool())
+	case reflect.String:
+		return quoteLogString(rv.String())
+	}
+	return quoteLogString(fmt.Sprint(v))
+}
+
+// quoteLogString quotes s in the way of mysql_real_escape_string
+func quoteLogString(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			sb.WriteString(`\0`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\x1a':
+			sb.WriteString(`\Z`)
+		case '\\', '\'', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
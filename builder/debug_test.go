@@ -0,0 +1,153 @@
+package builder
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLogValue(t *testing.T) {
+	var (
+		nilPtr *int
+		i      = 5
+	)
+	var data = []struct {
+		in  interface{}
+		out string
+	}{
+		{in: nil, out: "NULL"},
+		{in: nilPtr, out: "NULL"},
+		{in: &i, out: "5"},
+		{in: true, out: "1"},
+		{in: false, out: "0"},
+		{in: -12, out: "-12"},
+		{in: uint8(200), out: "200"},
+		{in: 1.5, out: "1.5"},
+		{in: float32(0.25), out: "0.25"},
+		{in: "it's \"ok\"\n\\\x00\x1a", out: `'it\'s \"ok\"\n\\\0\Z'`},
+		{in: []byte("ab"), out: "X'6162'"},
+		{in: []byte(nil), out: "NULL"},
+		{in: time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC), out: "'2020-01-02 03:04:05.6'"},
+		{in: time.Time{}, out: "'0000-00-00'"},
+		{in: sql.NullString{String: "x", Valid: true}, out: "'x'"},
+		{in: sql.NullInt64{}, out: "NULL"},
+		{in: []int{1, 2}, out: "'[1 2]'"},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		ass.Equal(tc.out, formatLogValue(tc.in))
+	}
+}
+
+func TestInterpolateForLog(t *testing.T) {
+	SetRedactedColumns("password", "Token")
+	defer SetRedactedColumns()
+	var data = []struct {
+		build func() (string, []interface{}, error)
+		out   string
+	}{
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{
+					"name":          "deen",
+					"age in":        []int{1, 2},
+					"password":      "secret",
+					"u.token like":  "abc%",
+					"score between": []interface{}{1, 2},
+				}, []string{"id"})
+			},
+			out: "SELECT id FROM user WHERE (name='deen' AND password='***' AND age IN (1,2) AND u.token LIKE '***' AND (score BETWEEN 1 AND 2))",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildUpdate("user", map[string]interface{}{"id": 1}, map[string]interface{}{
+					"password": "new",
+					"visits":   Incr(1),
+				})
+			},
+			out: "UPDATE user SET password='***',visits=visits+1 WHERE (id=1)",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildInsertOnDuplicate("user", []map[string]interface{}{
+					{"name": "a", "password": "p1"},
+					{"name": "b", "password": "p2"},
+				}, map[string]interface{}{"visits": Incr(1)})
+			},
+			out: "INSERT INTO user (name,password) VALUES ('a','***'),('b','***') ON DUPLICATE KEY UPDATE visits=visits+1",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildBulkUpdate("user", "id", map[interface{}]map[string]interface{}{
+					1: {"password": "p1", "name": "a"},
+				}, nil)
+			},
+			out: "UPDATE user SET name=CASE id WHEN 1 THEN 'a' ELSE name END,password=CASE id WHEN 1 THEN '***' ELSE password END WHERE (id IN (1))",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildUpdate("user", map[string]interface{}{"id": 1}, map[string]interface{}{
+					"password": Expr("SHA2(CONCAT(?,salt),256)", "hunter2"),
+					"name":     Expr("UPPER(?)", "deen"),
+				})
+			},
+			out: "UPDATE user SET name=UPPER('deen'),password=SHA2(CONCAT('***',salt),256) WHERE (id=1)",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{
+					"_custom_1": Custom("password = MD5(?) AND name = LOWER(?)", "hunter2", "Deen"),
+					"token <>":  "x",
+				}, []string{"id"})
+			},
+			out: "SELECT id FROM user WHERE (password = MD5('***') AND name = LOWER('Deen') AND token!='***')",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{
+					"password regexp":   "^hun",
+					"token sounds like": "abc",
+					"name not regexp":   "^x",
+					"password not in":   []interface{}{"a", "b"},
+				}, []string{"id"})
+			},
+			out: "SELECT id FROM user WHERE (password NOT IN ('***','***') AND password REGEXP '***' AND name NOT REGEXP '^x' AND token SOUNDS LIKE '***')",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return "INSERT INTO user (name,password) VALUES (TRIM(?),SHA2(?,256))", []interface{}{" a ", "p1"}, nil
+			},
+			out: "INSERT INTO user (name,password) VALUES (TRIM(' a '),SHA2('***',256))",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return "UPDATE user SET password=CASE id WHEN ? THEN MD5(?) ELSE password END", []interface{}{1, "p1"}, nil
+			},
+			out: "UPDATE user SET password=CASE id WHEN 1 THEN MD5('***') ELSE password END",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return "SELECT * FROM t WHERE a='?' AND b=? -- ?\n AND `password`=? AND c=?", []interface{}{1, "x"}, nil
+			},
+			out: "SELECT * FROM t WHERE a='?' AND b=1 -- ?\n AND `password`='***' AND c=?",
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := tc.build()
+		ass.NoError(err)
+		ass.Equal(tc.out, InterpolateForLog(cond, vals))
+	}
+
+	// the JSON array bound for a large IN list belongs to the column before IN
+	SetInListLimit(2, InListJSONTable)
+	defer SetInListLimit(0, InListExpand)
+	cond, vals, err := BuildSelect("user", map[string]interface{}{
+		"password in": []interface{}{"a", "b", "c"},
+		"name in":     []interface{}{"x", "y", "z"},
+	}, []string{"id"})
+	ass.NoError(err)
+	ass.Equal("SELECT id FROM user WHERE (name IN (SELECT jt.v FROM JSON_TABLE('[\\\"x\\\",\\\"y\\\",\\\"z\\\"]','$[*]' COLUMNS(v VARCHAR(1) PATH '$')) AS jt) AND password IN (SELECT jt.v FROM JSON_TABLE('***','$[*]' COLUMNS(v VARCHAR(1) PATH '$')) AS jt))", InterpolateForLog(cond, vals))
+}
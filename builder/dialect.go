@@ -0,0 +1,57 @@
+package builder
+
+// Dialect describes the SQL features supported by the target database.
+// builder consults it whenever a statement could be written in more than one way
+type Dialect struct {
+	// Name is only used in error messages
+	Name string
+	// RowValue reports whether row constructors such as (a,b) IN ((?,?),(?,?)) are supported,
+	// otherwise they are expanded into OR chains
+	RowValue bool
+	// RowAlias reports whether the inserted row can be referenced by an alias
+	// (INSERT ... AS new ON DUPLICATE KEY UPDATE c=new.c, MySQL 8.0.19+),
+	// otherwise the deprecated VALUES(c) is used
+	RowAlias bool
+	// Returning reports whether INSERT ... RETURNING is supported,
+	// otherwise generated ids are computed from LastInsertId
+	Returning bool
+	// LockNoWait reports whether NOWAIT and SKIP LOCKED of locking reads are supported
+	LockNoWait bool
+	// LockOf reports whether FOR SHARE and FOR UPDATE OF table are supported,
+	// otherwise share locks are written as LOCK IN SHARE MODE
+	LockOf bool
+}
+
+var (
+	// MySQL is the default dialect
+	MySQL = Dialect{
+		Name:     "mysql",
+		RowValue: true,
+	}
+
+	// MySQL8 is MySQL 8.0.19 and later
+	MySQL8 = Dialect{
+		Name:       "mysql8",
+		RowValue:   true,
+		RowAlias:   true,
+		LockNoWait: true,
+		LockOf:     true,
+	}
+
+	// MariaDB is MariaDB 10.6 and later
+	MariaDB = Dialect{
+		Name:       "mariadb",
+		RowValue:   true,
+		Returning:  true,
+		LockNoWait: true,
+	}
+)
+
+// SetDialect sets the dialect used by all builders in the process, default is MySQL.
+// It's safe to call concurrently, but as it changes the SQL built by every package,
+// the application rather than a library should set it, once at startup
+func SetDialect(d Dialect) {
+	updateConfig(func(c *config) {
+		c.dialect = d
+	})
+}
@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// NormalizeSQL reduces sql to its shape, so that queries differing only in values share the same text:
+// comments are removed, whitespace is collapsed, everything is lower-cased,
+// string and number literals become ?, lists of placeholders like IN (?,?,?) become (?+)
+// and the repeated rows of VALUES (?,?),(?,?) are collapsed into one
+func NormalizeSQL(sql string) string {
+	tokens := make([]string, 0, 32)
+	for _, tok := range tokenizeSQL(sql) {
+		if tok.significant {
+			tokens = append(tokens, tok.text)
+		}
+	}
+	out := make([]string, 0, len(tokens))
+	var parens []int
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch c := t[0]; {
+		case '\'' == c || '"' == c:
+			t = "?"
+		case c >= '0' && c <= '9':
+			// the fraction of 1.5 is a separate token
+			if i+2 < len(tokens) && "." == tokens[i+1] && isDigit(tokens[i+2][0]) {
+				i += 2
+			}
+			t = "?"
+		case '-' == c && 1 == len(t) && i+1 < len(tokens) && isDigit(tokens[i+1][0]):
+			// the sign of a negative number, not a subtraction
+			if 0 == len(out) || (!isFingerprintWord(out[len(out)-1]) && ")" != out[len(out)-1]) {
+				continue
+			}
+		case '(' == c:
+			parens = append(parens, len(out))
+		case ')' == c:
+			if 0 == len(parens) {
+				break
+			}
+			begin := parens[len(parens)-1]
+			parens = parens[:len(parens)-1]
+			if !isPlaceholderList(out[begin+1:]) {
+				break
+			}
+			out = append(out[:begin], "(", "?+", ")")
+			// rows of a multi-row VALUES or tuples of a tuple IN
+			if n := len(out); n >= 7 && isPlaceholderGroup(out[n-7:n-4]) && "," == out[n-4] {
+				out = out[:n-4]
+			}
+			continue
+		default:
+			t = strings.ToLower(t)
+		}
+		out = append(out, t)
+	}
+	var sb strings.Builder
+	sb.Grow(len(sql))
+	for i, t := range out {
+		if i > 0 && isFingerprintWord(out[i-1]) && (isFingerprintWord(t) || '@' == t[0]) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t)
+	}
+	return sb.String()
+}
+
+// Fingerprint returns a short hash of NormalizeSQL(sql), which is stable for queries of the same shape
+// and can be used as a metric label or cache key
+func Fingerprint(sql string) string {
+	h := fnv.New64a()
+	h.Write([]byte(NormalizeSQL(sql)))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// isPlaceholderList reports whether tokens are like ?,?,? or (?+),(?+)
+func isPlaceholderList(tokens []string) bool {
+	if 0 == len(tokens) {
+		return false
+	}
+	for i := 0; i < len(tokens); {
+		switch {
+		case "?" == tokens[i]:
+			i++
+		case i+3 <= len(tokens) && isPlaceholderGroup(tokens[i:i+3]):
+			i += 3
+		default:
+			return false
+		}
+		if i < len(tokens) {
+			if "," != tokens[i] || i+1 == len(tokens) {
+				return false
+			}
+			i++
+		}
+	}
+	return true
+}
+
+func isPlaceholderGroup(tokens []string) bool {
+	return "(" == tokens[0] && "?+" == tokens[1] && ")" == tokens[2]
+}
+
+func isFingerprintWord(t string) bool {
+	return isIdentChar(t[0]) || '?' == t[0] || '`' == t[0]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,57 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	var data = []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  "SELECT id FROM tb WHERE (name=? AND age IN (?,?,?))",
+			out: "select id from tb where(name=? and age in(?+))",
+		},
+		{
+			in:  "select  id\n FROM tb /* hot */ WHERE name = 'deen' and score > -1.5 AND id IN (1, 2) -- tail",
+			out: "select id from tb where name=? and score>? and id in(?+)",
+		},
+		{
+			in:  "INSERT INTO tb (a,b) VALUES (?,?),(?,?),(?,?)",
+			out: "insert into tb(a,b)values(?+)",
+		},
+		{
+			in:  "SELECT * FROM tb WHERE (a,b) IN ((?,?),(?,?)) AND c-1>0 AND `Name`=\"x\"",
+			out: "select*from tb where(a,b)in(?+)and c-?>? and `name`=?",
+		},
+		{
+			in:  "SELECT @@auto_increment_increment, f(?) LIMIT ?,?",
+			out: "select @@auto_increment_increment,f(?+)limit ?,?",
+		},
+		{
+			in:  "SELECT * FROM tb WHERE a IN (?,x)",
+			out: "select*from tb where a in(?,x)",
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		ass.Equal(tc.out, NormalizeSQL(tc.in))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	ass := assert.New(t)
+	cond1, _, err := BuildSelect("tb", map[string]interface{}{"id in": []int{1, 2}, "name": "a"}, nil)
+	ass.NoError(err)
+	cond2, _, err := BuildSelect("tb", map[string]interface{}{"id in": []int{1, 2, 3, 4}, "name": "b"}, nil)
+	ass.NoError(err)
+	cond3, _, err := BuildSelect("tb", map[string]interface{}{"id in": []int{1}, "age": 1}, nil)
+	ass.NoError(err)
+	ass.Len(Fingerprint(cond1), 16)
+	ass.Equal(Fingerprint(cond1), Fingerprint(cond2))
+	ass.Equal(Fingerprint(cond1), Fingerprint("select * from tb where (name = 'x' and id in (1,2,3))"))
+	ass.NotEqual(Fingerprint(cond1), Fingerprint(cond3))
+}
@@ -0,0 +1,158 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	errIndexValueType = errors.New(`[builder] the value of "_index" must be of IndexHint or []IndexHint type`)
+	errHintValueType  = errors.New(`[builder] the value of "_hint" must be of string or []string type`)
+
+	errIndexName = `[builder] "%s" is not a valid index name`
+	errHintName  = `[builder] "%s" is not a supported optimizer hint`
+)
+
+// IndexHint is the value of _index, which tells the optimizer which indexes to choose
+type IndexHint struct {
+	action  string
+	indexes []string
+}
+
+// UseIndex builds USE INDEX (indexes), an empty indexes means using no index
+// usage where := map[string]interface{}{"_index": builder.UseIndex("idx_a")}
+func UseIndex(indexes ...string) IndexHint {
+	return IndexHint{action: "USE", indexes: indexes}
+}
+
+// ForceIndex builds FORCE INDEX (indexes)
+// usage where := map[string]interface{}{"_index": builder.ForceIndex("idx_a")}
+func ForceIndex(indexes ...string) IndexHint {
+	return IndexHint{action: "FORCE", indexes: indexes}
+}
+
+// IgnoreIndex builds IGNORE INDEX (indexes)
+// usage where := map[string]interface{}{"_index": builder.IgnoreIndex("idx_a")}
+func IgnoreIndex(indexes ...string) IndexHint {
+	return IndexHint{action: "IGNORE", indexes: indexes}
+}
+
+func (h IndexHint) build() (string, error) {
+	if "USE" != h.action && 0 == len(h.indexes) {
+		return "", fmt.Errorf(errIndexName, "")
+	}
+	for _, idx := range h.indexes {
+		if !isIdentifier(idx) {
+			return "", fmt.Errorf(errIndexName, idx)
+		}
+	}
+	return h.action + " INDEX (" + strings.Join(h.indexes, ",") + ")", nil
+}
+
+func resolveIndexHint(val interface{}) (string, error) {
+	var hints []IndexHint
+	switch v := val.(type) {
+	case IndexHint:
+		hints = []IndexHint{v}
+	case []IndexHint:
+		hints = v
+	default:
+		return "", errIndexValueType
+	}
+	parts := make([]string, 0, len(hints))
+	for _, h := range hints {
+		s, err := h.build()
+		if nil != err {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// optimizerHints are the optimizer hints of MySQL 8.0
+var optimizerHints = map[string]struct{}{
+	"BKA": {}, "NO_BKA": {}, "BNL": {}, "NO_BNL": {},
+	"DERIVED_CONDITION_PUSHDOWN": {}, "NO_DERIVED_CONDITION_PUSHDOWN": {},
+	"GROUP_INDEX": {}, "NO_GROUP_INDEX": {}, "HASH_JOIN": {}, "NO_HASH_JOIN": {},
+	"INDEX": {}, "NO_INDEX": {}, "INDEX_MERGE": {}, "NO_INDEX_MERGE": {},
+	"JOIN_FIXED_ORDER": {}, "JOIN_INDEX": {}, "NO_JOIN_INDEX": {},
+	"JOIN_ORDER": {}, "JOIN_PREFIX": {}, "JOIN_SUFFIX": {},
+	"MAX_EXECUTION_TIME": {}, "MERGE": {}, "NO_MERGE": {}, "MRR": {}, "NO_MRR": {},
+	"NO_ICP": {}, "NO_RANGE_OPTIMIZATION": {}, "ORDER_INDEX": {}, "NO_ORDER_INDEX": {},
+	"QB_NAME": {}, "RESOURCE_GROUP": {}, "SEMIJOIN": {}, "NO_SEMIJOIN": {},
+	"SET_VAR": {}, "SKIP_SCAN": {}, "NO_SKIP_SCAN": {}, "SUBQUERY": {},
+}
+
+// resolveOptimizerHint validates the value of _hint and joins the hints,
+// every hint is a known name optionally followed by its arguments in parentheses, e.g. MAX_EXECUTION_TIME(1000)
+func resolveOptimizerHint(val interface{}) (string, error) {
+	var hints []string
+	switch v := val.(type) {
+	case string:
+		hints = []string{v}
+	case []string:
+		hints = v
+	default:
+		return "", errHintValueType
+	}
+	parts := make([]string, 0, len(hints))
+	for _, h := range hints {
+		h = strings.TrimSpace(h)
+		if "" == h {
+			continue
+		}
+		if !isValidHint(h) {
+			return "", fmt.Errorf(errHintName, h)
+		}
+		parts = append(parts, h)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func isValidHint(h string) bool {
+	name, args := h, ""
+	if idx := strings.IndexByte(h, '('); idx >= 0 {
+		name, args = strings.TrimSpace(h[:idx]), h[idx:]
+		if !strings.HasSuffix(args, ")") {
+			return false
+		}
+		args = args[1 : len(args)-1]
+	}
+	if _, ok := optimizerHints[strings.ToUpper(name)]; !ok {
+		return false
+	}
+	// arguments are identifiers, numbers and simple expressions like SET_VAR(sort_buffer_size=16M),
+	// anything able to end the comment or the statement is rejected
+	for i := 0; i < len(args); i++ {
+		c := args[i]
+		if !isIdentChar(c) && !strings.ContainsRune(" ,.=@-'", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifier reports whether s is a plain unquoted identifier
+// isColumnName reports whether s is a column optionally qualified by its table, e.g. t.id
+func isColumnName(s string) bool {
+	for _, part := range strings.Split(s, ".") {
+		if !isIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifier(s string) bool {
+	if "" == s {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSelectHint(t *testing.T) {
+	var data = []struct {
+		where map[string]interface{}
+		cond  string
+		err   error
+	}{
+		{
+			where: map[string]interface{}{"a": 1, "_index": ForceIndex("idx_a")},
+			cond:  "SELECT id FROM tb FORCE INDEX (idx_a) WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_index": []IndexHint{UseIndex("idx_a", "idx_b"), IgnoreIndex("PRIMARY")}},
+			cond:  "SELECT id FROM tb USE INDEX (idx_a,idx_b) IGNORE INDEX (PRIMARY) WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_index": UseIndex()},
+			cond:  "SELECT id FROM tb USE INDEX () WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_hint": "MAX_EXECUTION_TIME(1000)"},
+			cond:  "SELECT /*+ MAX_EXECUTION_TIME(1000) */ id FROM tb WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{
+				"a":      1,
+				"_hint":  []string{"max_execution_time(1000)", " SET_VAR(sort_buffer_size = 16M) ", "NO_ICP(tb idx_a)"},
+				"_index": ForceIndex("idx_a"),
+			},
+			cond: "SELECT /*+ max_execution_time(1000) SET_VAR(sort_buffer_size = 16M) NO_ICP(tb idx_a) */ id FROM tb FORCE INDEX (idx_a) WHERE (a=?)",
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_index": ForceIndex("idx_a) WHERE 1=1 --")},
+			err:   errors.New(`[builder] "idx_a) WHERE 1=1 --" is not a valid index name`),
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_index": ForceIndex()},
+			err:   errors.New(`[builder] "" is not a valid index name`),
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_index": "idx_a"},
+			err:   errIndexValueType,
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_hint": "FAST_PLEASE"},
+			err:   errors.New(`[builder] "FAST_PLEASE" is not a supported optimizer hint`),
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_hint": "MAX_EXECUTION_TIME(1) */ DROP"},
+			err:   errors.New(`[builder] "MAX_EXECUTION_TIME(1) */ DROP" is not a supported optimizer hint`),
+		},
+		{
+			where: map[string]interface{}{"a": 1, "_hint": 1000},
+			err:   errHintValueType,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, _, err := BuildSelect("tb", tc.where, []string{"id"})
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+	}
+}
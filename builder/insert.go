@@ -0,0 +1,212 @@
+package builder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	errInsertIDColumn   = errors.New("[builder] the id column of insert must not be empty")
+	errInsertStructType = errors.New("[builder] the data of InsertStructs must be a slice of struct pointers")
+	errInsertIDField    = `[builder] no field of %s maps to the id column "%s"`
+	errInsertIDGiven    = `[builder] the id column "%s" is generated and can't be given in data`
+	errInsertIDNotZero  = `[builder] the id of structs[%d] is %v, ids are generated and must be zero`
+	errInsertIDsMissing = `[builder] %d rows inserted but %d expected, generated ids are unknown`
+	errInsertIDType     = `[builder] can't set id %d to field of type %s`
+)
+
+// ExecQueryer is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type ExecQueryer interface {
+	Execer
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// InsertWithIDs executes the insert built by BuildInsert and returns the generated ids of all rows
+// in the order of data.
+// If the dialect supports Returning, ids are read from INSERT ... RETURNING idColumn.
+// Otherwise they are computed from LastInsertId, which is the id of the first row, and
+// @@auto_increment_increment, which requires innodb_autoinc_lock_mode 0 or 1 for multi-row inserts
+// because ids of one statement may interleave with others in mode 2.
+// data must not contain idColumn, an explicit id would break the computation
+func InsertWithIDs(ctx context.Context, db ExecQueryer, table, idColumn string, data []map[string]interface{}) ([]int64, error) {
+	if "" == idColumn {
+		return nil, errInsertIDColumn
+	}
+	for _, row := range data {
+		if _, ok := row[idColumn]; ok {
+			return nil, fmt.Errorf(errInsertIDGiven, idColumn)
+		}
+	}
+	cond, vals, err := BuildInsert(table, data)
+	if nil != err {
+		return nil, err
+	}
+	if loadConfig().dialect.Returning {
+		return insertReturning(ctx, db, cond+" RETURNING "+quoteField(idColumn), vals, len(data))
+	}
+	res, err := db.ExecContext(ctx, cond, vals...)
+	if nil != err {
+		return nil, err
+	}
+	affected, err := res.RowsAffected()
+	if nil != err {
+		return nil, err
+	}
+	if affected != int64(len(data)) {
+		return nil, fmt.Errorf(errInsertIDsMissing, affected, len(data))
+	}
+	first, err := res.LastInsertId()
+	if nil != err {
+		return nil, err
+	}
+	step := int64(1)
+	if len(data) > 1 {
+		if step, err = autoIncrementIncrement(ctx, db); nil != err {
+			return nil, err
+		}
+	}
+	ids := make([]int64, len(data))
+	for i := range ids {
+		ids[i] = first + int64(i)*step
+	}
+	return ids, nil
+}
+
+func insertReturning(ctx context.Context, db ExecQueryer, cond string, vals []interface{}, size int) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, cond, vals...)
+	if nil != err {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]int64, 0, size)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); nil != err {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); nil != err {
+		return nil, err
+	}
+	if len(ids) != size {
+		return nil, fmt.Errorf(errInsertIDsMissing, len(ids), size)
+	}
+	return ids, nil
+}
+
+func autoIncrementIncrement(ctx context.Context, db ExecQueryer) (int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT @@auto_increment_increment")
+	if nil != err {
+		return 0, err
+	}
+	defer rows.Close()
+	step := int64(1)
+	for rows.Next() {
+		if err = rows.Scan(&step); nil != err {
+			return 0, err
+		}
+	}
+	return step, rows.Err()
+}
+
+// InsertStructs inserts structs, which must be a slice of struct pointers, like InsertWithIDs and
+// writes the generated ids back into the field mapped to idColumn.
+// Columns are taken from the ddb tag or the field name. The id column is left out of
+// the insert, so the id field must be zero in all structs
+func InsertStructs(ctx context.Context, db ExecQueryer, table, idColumn string, structs interface{}) ([]int64, error) {
+	if "" == idColumn {
+		return nil, errInsertIDColumn
+	}
+	rv := reflect.ValueOf(structs)
+	if rv.Kind() != reflect.Slice {
+		return nil, errInsertStructType
+	}
+	data := make([]map[string]interface{}, rv.Len())
+	idFields := make([]reflect.Value, rv.Len())
+	for i := range data {
+		elem := rv.Index(i)
+		if elem.Kind() != reflect.Ptr || elem.IsNil() || elem.Elem().Kind() != reflect.Struct {
+			return nil, errInsertStructType
+		}
+		elem = elem.Elem()
+		idField, key, ok := findNamedField(elem, idColumn)
+		if !ok {
+			return nil, fmt.Errorf(errInsertIDField, elem.Type(), idColumn)
+		}
+		if !isZero(idField) {
+			return nil, fmt.Errorf(errInsertIDNotZero, i, idField.Interface())
+		}
+		idFields[i] = idField
+		data[i] = make(map[string]interface{})
+		structToNamedMap(elem, data[i])
+		delete(data[i], key)
+	}
+	ids, err := InsertWithIDs(ctx, db, table, idColumn, data)
+	if nil != err {
+		return nil, err
+	}
+	for i, f := range idFields {
+		if err = setID(f, ids[i]); nil != err {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// findNamedField finds the field keyed by name in the same way as structToNamedMap and
+// returns it with its key, a field without tag also matches name case-insensitively
+func findNamedField(v reflect.Value, name string) (reflect.Value, string, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if f, key, ok := findNamedField(v.Field(i), name); ok {
+				return f, key, true
+			}
+			continue
+		}
+		if "" != field.PkgPath {
+			continue
+		}
+		tag := field.Tag.Get(namedTagName)
+		if idx := strings.IndexByte(tag, ','); idx >= 0 {
+			tag = tag[:idx]
+		}
+		if "-" == tag {
+			continue
+		}
+		if "" != tag {
+			if tag == name {
+				return v.Field(i), tag, true
+			}
+			continue
+		}
+		if strings.EqualFold(field.Name, name) {
+			return v.Field(i), field.Name, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+func setID(f reflect.Value, id int64) error {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.OverflowInt(id) {
+			break
+		}
+		f.SetInt(id)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if id < 0 || f.OverflowUint(uint64(id)) {
+			break
+		}
+		f.SetUint(uint64(id))
+		return nil
+	}
+	return fmt.Errorf(errInsertIDType, id, f.Type())
+}
@@ -0,0 +1,104 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertWithIDs(t *testing.T) {
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	data := []map[string]interface{}{
+		{"name": "deen"},
+		{"name": "Tony"},
+		{"name": "Ann"},
+	}
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO tb \\(name\\) VALUES \\(\\?\\),\\(\\?\\),\\(\\?\\)").
+		WithArgs("deen", "Tony", "Ann").WillReturnResult(sqlmock.NewResult(11, 3))
+	mock.ExpectQuery("SELECT @@auto_increment_increment").
+		WillReturnRows(sqlmock.NewRows([]string{"@@auto_increment_increment"}).AddRow(2))
+	ids, err := InsertWithIDs(ctx, db, "tb", "id", data)
+	ass.NoError(err)
+	ass.Equal([]int64{11, 13, 15}, ids)
+
+	mock.ExpectExec("INSERT INTO tb \\(name\\) VALUES \\(\\?\\)").
+		WithArgs("deen").WillReturnResult(sqlmock.NewResult(7, 1))
+	ids, err = InsertWithIDs(ctx, db, "tb", "id", data[:1])
+	ass.NoError(err)
+	ass.Equal([]int64{7}, ids)
+
+	mock.ExpectExec("INSERT INTO tb").WillReturnResult(sqlmock.NewResult(7, 2))
+	_, err = InsertWithIDs(ctx, db, "tb", "id", data)
+	ass.Equal(errors.New("[builder] 2 rows inserted but 3 expected, generated ids are unknown"), err)
+
+	SetDialect(MariaDB)
+	defer SetDialect(MySQL)
+	mock.ExpectQuery("INSERT INTO tb \\(name\\) VALUES \\(\\?\\),\\(\\?\\),\\(\\?\\) RETURNING id").
+		WithArgs("deen", "Tony", "Ann").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(8).AddRow(5))
+	ids, err = InsertWithIDs(ctx, db, "tb", "id", data)
+	ass.NoError(err)
+	ass.Equal([]int64{3, 8, 5}, ids)
+
+	_, err = InsertWithIDs(ctx, db, "tb", "", data)
+	ass.Equal(errInsertIDColumn, err)
+	_, err = InsertWithIDs(ctx, db, "tb", "id", []map[string]interface{}{{"name": "deen"}, {"id": 100, "name": "Tony"}})
+	ass.EqualError(err, `[builder] the id column "id" is generated and can't be given in data`)
+	ass.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInsertStructs(t *testing.T) {
+	type Base struct {
+		ID uint64
+	}
+	type user struct {
+		Base
+		Name   string `ddb:"name"`
+		Secret string `ddb:"-"`
+	}
+	type account struct {
+		UID  int32  `ddb:"uid"`
+		Mail string `ddb:"mail"`
+	}
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	ctx := context.Background()
+
+	users := []*user{{Name: "deen", Secret: "x"}, {Name: "Tony"}}
+	mock.ExpectExec("INSERT INTO tb \\(name\\) VALUES \\(\\?\\),\\(\\?\\)").
+		WithArgs("deen", "Tony").WillReturnResult(sqlmock.NewResult(21, 2))
+	mock.ExpectQuery("SELECT @@auto_increment_increment").
+		WillReturnRows(sqlmock.NewRows([]string{"@@auto_increment_increment"}).AddRow(1))
+	ids, err := InsertStructs(ctx, db, "tb", "id", users)
+	ass.NoError(err)
+	ass.Equal([]int64{21, 22}, ids)
+	ass.Equal(uint64(21), users[0].ID)
+	ass.Equal(uint64(22), users[1].ID)
+
+	accounts := []*account{{Mail: "a@b.c"}}
+	mock.ExpectExec("INSERT INTO tb \\(mail\\) VALUES \\(\\?\\)").
+		WithArgs("a@b.c").WillReturnResult(sqlmock.NewResult(5, 1))
+	ids, err = InsertStructs(ctx, db, "tb", "uid", accounts)
+	ass.NoError(err)
+	ass.Equal([]int64{5}, ids)
+	ass.Equal(int32(5), accounts[0].UID)
+
+	// explicit ids are rejected rather than overwritten
+	users = []*user{{Name: "deen"}, {Base: Base{ID: 100}, Name: "Tony"}}
+	_, err = InsertStructs(ctx, db, "tb", "id", users)
+	ass.EqualError(err, `[builder] the id of structs[1] is 100, ids are generated and must be zero`)
+	ass.Equal(uint64(100), users[1].ID)
+
+	_, err = InsertStructs(ctx, db, "tb", "id", []user{{Name: "deen"}})
+	ass.Equal(errInsertStructType, err)
+	_, err = InsertStructs(ctx, db, "tb", "id", accounts)
+	ass.EqualError(err, `[builder] no field of builder.account maps to the id column "id"`)
+	ass.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,691 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	errNamedDataType   = errors.New("[builder] data of NamedQueryStruct must be a map, a struct or a slice of them")
+	errNamedEmptyBulk  = errors.New("[builder] data of NamedQueryStruct can't be an empty slice")
+	errNamedBulkValues = errors.New("[builder] a slice as data requires the sql to contain VALUES (...)")
+	errNamedBulkParam  = errors.New("[builder] a slice as data only binds params inside VALUES (...)")
+	errNamedBlock      = errors.New("[builder] unbalanced [[ ]] in NamedQuery")
+)
+
+const namedTagName = "ddb"
+
+type paramKind uint8
+
+const (
+	textSegment paramKind = iota
+	// {{name}}
+	braceParam
+	// :name
+	colonParam
+	// @name, it's left as a user variable if name isn't in data
+	atParam
+	// [[ begins an optional block, which is only emitted if all params in it are present and non-empty
+	blockBegin
+	// ]]
+	blockEnd
+)
+
+type namedSegment struct {
+	kind paramKind
+	// text of a textSegment or name of a param
+	text string
+	// distance from a blockBegin to its blockEnd
+	skip int
+}
+
+// namedTemplate is the parsed form of the sql passed to NamedQuery
+type namedTemplate struct {
+	segments []namedSegment
+	// segments[valuesBegin:valuesEnd] is the first group after VALUES, which is repeated for bulk data
+	valuesBegin, valuesEnd int
+	// length of the sql, used for preallocating the result
+	size int
+}
+
+// parseNamed splits sql into text, params and optional blocks,
+// quoted strings, identifiers and comments are never parsed
+func parseNamed(sql string) (*namedTemplate, error) {
+	t := &namedTemplate{valuesBegin: -1, valuesEnd: -1, size: len(sql)}
+	var blocks []int
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			t.segments = append(t.segments, namedSegment{kind: textSegment, text: text.String()})
+			text.Reset()
+		}
+	}
+	addParam := func(kind paramKind, name string) {
+		flush()
+		t.segments = append(t.segments, namedSegment{kind: kind, text: name})
+	}
+	n := len(sql)
+	depth, valuesDepth, valuesBlocks := 0, -1, 0
+	expectValues := false
+	for i := 0; i < n; {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := skipQuoted(sql, i)
+			text.WriteString(sql[i:j])
+			i = j
+			continue
+		case c == '#' || (c == '-' && strings.HasPrefix(sql[i:], "--") && (i+2 == n || isSpace(sql[i+2]))):
+			j := strings.IndexByte(sql[i:], '\n')
+			if j < 0 {
+				j = n - i
+			}
+			text.WriteString(sql[i : i+j])
+			i += j
+			continue
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				j = n
+			} else {
+				j += i + 4
+			}
+			text.WriteString(sql[i:j])
+			i = j
+			continue
+		case c == '[' && strings.HasPrefix(sql[i:], "[["):
+			flush()
+			blocks = append(blocks, len(t.segments))
+			t.segments = append(t.segments, namedSegment{kind: blockBegin})
+			i += 2
+			continue
+		case c == ']' && strings.HasPrefix(sql[i:], "]]"):
+			if 0 == len(blocks) {
+				return nil, errNamedBlock
+			}
+			flush()
+			begin := blocks[len(blocks)-1]
+			blocks = blocks[:len(blocks)-1]
+			t.segments[begin].skip = len(t.segments) - begin
+			t.segments = append(t.segments, namedSegment{kind: blockEnd})
+			i += 2
+			continue
+		case c == '{' && strings.HasPrefix(sql[i:], "{{"):
+			j := strings.Index(sql[i+2:], "}}")
+			if j > 0 && strings.IndexFunc(sql[i+2:i+2+j], isSpaceRune) < 0 {
+				addParam(braceParam, sql[i+2:i+2+j])
+				i += j + 4
+				expectValues = false
+				continue
+			}
+		case c == ':' && i+1 < n && sql[i+1] == ':':
+			// a cast like a::int
+			text.WriteString("::")
+			i += 2
+			continue
+		case (c == ':' || c == '@') && i+1 < n && isIdentStart(sql[i+1]):
+			j := scanIdent(sql, i+1)
+			kind := colonParam
+			if c == '@' {
+				kind = atParam
+			}
+			addParam(kind, sql[i+1:j])
+			i = j
+			expectValues = false
+			continue
+		case c == '@' && strings.HasPrefix(sql[i:], "@@"):
+			// a system variable like @@session.sql_mode
+			j := i + 2
+			for j < n && (isIdentChar(sql[j]) || sql[j] == '.') {
+				j++
+			}
+			text.WriteString(sql[i:j])
+			i = j
+			continue
+		case isIdentStart(c) && (0 == i || !isIdentChar(sql[i-1])):
+			j := scanIdent(sql, i)
+			word := sql[i:j]
+			expectValues = strings.EqualFold(word, "VALUES") || strings.EqualFold(word, "VALUE")
+			text.WriteString(word)
+			i = j
+			continue
+		case c == '(':
+			depth++
+			if expectValues && t.valuesBegin < 0 {
+				flush()
+				t.valuesBegin = len(t.segments)
+				valuesDepth = depth
+				valuesBlocks = len(blocks)
+			}
+		case c == ')':
+			if depth == valuesDepth {
+				text.WriteByte(c)
+				flush()
+				t.valuesEnd = len(t.segments)
+				valuesDepth = -1
+				if valuesBlocks != len(blocks) {
+					// the group overlaps an optional block, so it can't be repeated
+					t.valuesBegin, t.valuesEnd = -1, -1
+				}
+				depth--
+				i++
+				continue
+			}
+			depth--
+		}
+		if !isSpace(c) {
+			expectValues = false
+		}
+		text.WriteByte(c)
+		i++
+	}
+	if 0 != len(blocks) {
+		return nil, errNamedBlock
+	}
+	flush()
+	return t, nil
+}
+
+// skipQuoted returns the index right after the quoted string beginning at sql[i]
+func skipQuoted(sql string, i int) int {
+	q := sql[i]
+	n := len(sql)
+	for j := i + 1; j < n; j++ {
+		switch sql[j] {
+		case '\\':
+			if q != '`' {
+				j++
+			}
+		case q:
+			if j+1 < n && sql[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return n
+}
+
+func scanIdent(sql string, i int) int {
+	for i < len(sql) && isIdentChar(sql[i]) {
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isSpaceRune(r rune) bool {
+	return r < 0x80 && isSpace(byte(r))
+}
+
+// parsed templates are cached by sql, the cache is simply dropped when it's full
+const namedCacheSize = 1024
+
+var (
+	namedCacheLock sync.RWMutex
+	namedCache     = make(map[string]*namedTemplate)
+)
+
+// getNamedTemplate returns the cached template of sql, or parses it if it's not cached
+func getNamedTemplate(sql string) (*namedTemplate, error) {
+	namedCacheLock.RLock()
+	t, ok := namedCache[sql]
+	namedCacheLock.RUnlock()
+	if ok {
+		return t, nil
+	}
+	t, err := parseNamed(sql)
+	if nil != err {
+		return nil, err
+	}
+	namedCacheLock.Lock()
+	if len(namedCache) >= namedCacheSize {
+		namedCache = make(map[string]*namedTemplate)
+	}
+	namedCache[sql] = t
+	namedCacheLock.Unlock()
+	return t, nil
+}
+
+// bind replaces params with placeholders and collects the values from data
+func (t *namedTemplate) bind(data map[string]interface{}) (string, []interface{}, error) {
+	b := &namedBinding{vals: make([]interface{}, 0, len(data))}
+	b.sb.Grow(t.size)
+	if err := b.bindSegments(t.segments, data); nil != err {
+		return "", nil, err
+	}
+	return b.result()
+}
+
+// bindStruct binds data of NamedQueryStruct
+func (t *namedTemplate) bindStruct(data interface{}) (string, []interface{}, error) {
+	items, bulk, err := namedData(data)
+	if nil != err {
+		return "", nil, err
+	}
+	if bulk {
+		return t.bindBulk(items)
+	}
+	return t.bind(items[0])
+}
+
+// bindBulk repeats the group after VALUES for every element of data
+func (t *namedTemplate) bindBulk(data []map[string]interface{}) (string, []interface{}, error) {
+	if t.valuesBegin < 0 || t.valuesEnd < 0 {
+		return "", nil, errNamedBulkValues
+	}
+	for i, seg := range t.segments {
+		if (i < t.valuesBegin || i >= t.valuesEnd) && (seg.kind == braceParam || seg.kind == colonParam) {
+			return "", nil, errNamedBulkParam
+		}
+	}
+	b := &namedBinding{}
+	b.sb.Grow(t.size)
+	if err := b.bindSegments(t.segments[:t.valuesBegin], nil); nil != err {
+		return "", nil, err
+	}
+	for i, item := range data {
+		if i > 0 {
+			b.sb.WriteByte(',')
+		}
+		if err := b.bindSegments(t.segments[t.valuesBegin:t.valuesEnd], item); nil != err {
+			return "", nil, err
+		}
+	}
+	if err := b.bindSegments(t.segments[t.valuesEnd:], nil); nil != err {
+		return "", nil, err
+	}
+	return b.result()
+}
+
+// namedBinding is the state of binding data to a namedTemplate
+type namedBinding struct {
+	sb   strings.Builder
+	vals []interface{}
+	// names of missing params in the order they appear
+	missing []string
+	// whether any optional block is left out
+	omitted bool
+}
+
+func (b *namedBinding) result() (string, []interface{}, error) {
+	if 0 != len(b.missing) {
+		return "", nil, fmt.Errorf("%s not found", strings.Join(b.missing, ","))
+	}
+	if 0 == len(b.vals) {
+		b.vals = nil
+	}
+	if b.omitted {
+		return removeDanglingKeywords(b.sb.String()), b.vals, nil
+	}
+	return b.sb.String(), b.vals, nil
+}
+
+// bindSegments writes segments, missing params are collected rather than returned
+func (b *namedBinding) bindSegments(segments []namedSegment, data map[string]interface{}) error {
+	for i := 0; i < len(segments); i++ {
+		seg := segments[i]
+		switch seg.kind {
+		case textSegment:
+			b.sb.WriteString(seg.text)
+			continue
+		case blockBegin:
+			if !isBlockPresent(segments[i+1:i+seg.skip], data) {
+				b.omitted = true
+				i += seg.skip
+			}
+			continue
+		case blockEnd:
+			continue
+		}
+		val, ok := data[seg.text]
+		if !ok {
+			if seg.kind == atParam {
+				b.sb.WriteString("@" + seg.text)
+			} else if !isStringInSlice(seg.text, b.missing) {
+				b.missing = append(b.missing, seg.text)
+			}
+			continue
+		}
+		if err := b.bindValue(seg.text, val); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *namedBinding) bindValue(name string, val interface{}) error {
+	switch v := val.(type) {
+	case nil, []byte:
+		b.vals = append(b.vals, val)
+		b.sb.WriteString(paramPlaceHolder)
+		return nil
+	case Comparable:
+		cond, condVals := whereConnector("AND", v)
+		return bindCondition(&b.sb, &b.vals, cond, condVals)
+	case map[string]interface{}:
+		comparables, err := getWhereConditions(v, defaultIgnoreKeys)
+		if nil != err {
+			return err
+		}
+		cond, condVals := whereConnector("AND", comparables...)
+		return bindCondition(&b.sb, &b.vals, cond, condVals)
+	}
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Slice {
+		b.vals = append(b.vals, val)
+		b.sb.WriteString(paramPlaceHolder)
+		return nil
+	}
+	length := v.Len()
+	if 0 == length {
+		// `IN ` followed by nothing is a syntax error
+		return fmt.Errorf(errEmptySliceCondition, name)
+	}
+	b.sb.WriteByte('(')
+	for i := 0; i < length; i++ {
+		if i > 0 {
+			b.sb.WriteByte(',')
+		}
+		b.sb.WriteString(paramPlaceHolder)
+		b.vals = append(b.vals, v.Index(i).Interface())
+	}
+	b.sb.WriteByte(')')
+	return nil
+}
+
+// bindCondition writes a condition built from a Comparable or a where map, an empty one is always true
+func bindCondition(sb *strings.Builder, vals *[]interface{}, cond string, condVals []interface{}) error {
+	if "" == cond {
+		cond = "1=1"
+	}
+	sb.WriteString(cond)
+	*vals = append(*vals, condVals...)
+	return nil
+}
+
+// isBlockPresent reports whether all params directly in the block are present and non-empty,
+// nested blocks decide for themselves
+func isBlockPresent(segments []namedSegment, data map[string]interface{}) bool {
+	for i := 0; i < len(segments); i++ {
+		seg := segments[i]
+		switch seg.kind {
+		case textSegment, blockEnd:
+			continue
+		case blockBegin:
+			i += seg.skip
+			continue
+		}
+		val, ok := data[seg.text]
+		if !ok {
+			if seg.kind == atParam {
+				continue
+			}
+			return false
+		}
+		if isEmptyParam(val) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEmptyParam(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case []byte:
+		return false
+	case Comparable:
+		cond, _ := v.Build()
+		return 0 == len(cond)
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return 0 == v.Len()
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+var (
+	// keywords which can't be followed by a condition
+	clauseEndKeywords = map[string]struct{}{
+		"ORDER": {}, "GROUP": {}, "HAVING": {}, "LIMIT": {}, "UNION": {},
+		"WINDOW": {}, "FOR": {}, "LOCK": {}, ")": {}, ";": {},
+	}
+	conditionKeywords = map[string]struct{}{
+		"WHERE": {}, "HAVING": {}, "ON": {}, "(": {},
+	}
+)
+
+type sqlToken struct {
+	text string
+	// significant tokens are neither spaces nor comments
+	significant bool
+}
+
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	n := len(sql)
+	for i := 0; i < n; {
+		c := sql[i]
+		j := i + 1
+		significant := true
+		switch {
+		case isSpace(c):
+			for j < n && isSpace(sql[j]) {
+				j++
+			}
+			significant = false
+		case c == '\'' || c == '"' || c == '`':
+			j = skipQuoted(sql, i)
+		case c == '#' || (c == '-' && strings.HasPrefix(sql[i:], "--") && (i+2 == n || isSpace(sql[i+2]))):
+			if k := strings.IndexByte(sql[i:], '\n'); k >= 0 {
+				j = i + k
+			} else {
+				j = n
+			}
+			significant = false
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if k := strings.Index(sql[i+2:], "*/"); k >= 0 {
+				j = i + k + 4
+			} else {
+				j = n
+			}
+			significant = false
+		case isIdentChar(c):
+			j = scanIdent(sql, i)
+		}
+		tokens = append(tokens, sqlToken{text: sql[i:j], significant: significant})
+		i = j
+	}
+	return tokens
+}
+
+// removeDanglingKeywords removes the AND,OR,WHERE left alone by omitted optional blocks,
+// e.g. `WHERE AND a=?` => `WHERE a=?`, `WHERE ORDER BY a` => `ORDER BY a`
+func removeDanglingKeywords(sql string) string {
+	tokens := tokenizeSQL(sql)
+	removed := make([]bool, len(tokens))
+	keyword := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return strings.ToUpper(tokens[i].text)
+	}
+	prevSignificant := func(i int) int {
+		for i--; i >= 0; i-- {
+			if tokens[i].significant && !removed[i] {
+				return i
+			}
+		}
+		return -1
+	}
+	nextSignificant := func(i int) int {
+		for i++; i < len(tokens); i++ {
+			if tokens[i].significant && !removed[i] {
+				return i
+			}
+		}
+		return len(tokens)
+	}
+	remove := func(i int) {
+		removed[i] = true
+		// take the preceding spaces away as well
+		if i > 0 && !tokens[i-1].significant && "" == strings.TrimSpace(tokens[i-1].text) {
+			removed[i-1] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, tok := range tokens {
+			if removed[i] || !tok.significant {
+				continue
+			}
+			word := keyword(i)
+			prev, next := keyword(prevSignificant(i)), keyword(nextSignificant(i))
+			_, prevIsCondKw := conditionKeywords[prev]
+			_, nextIsEnd := clauseEndKeywords[next]
+			switch word {
+			case "AND", "OR":
+				if "" == prev || prevIsCondKw || "AND" == prev || "OR" == prev || "" == next || nextIsEnd {
+					remove(i)
+					changed = true
+				}
+			case "WHERE", "HAVING":
+				if "" == next || nextIsEnd {
+					remove(i)
+					changed = true
+				}
+			case "(":
+				// a parenthesis emptied by omitted blocks, but not the one of a call like NOW()
+				if j := nextSignificant(i); ")" == next && (prevIsCondKw || "AND" == prev || "OR" == prev || "NOT" == prev) {
+					if "NOT" == prev {
+						remove(prevSignificant(i))
+					}
+					remove(i)
+					remove(j)
+					changed = true
+				}
+			}
+		}
+	}
+	// omitted blocks leave their surrounding spaces behind
+	var sb strings.Builder
+	lastSpace := true
+	for i, tok := range tokens {
+		if removed[i] {
+			continue
+		}
+		if !tok.significant && "" == strings.TrimSpace(tok.text) {
+			next := nextSignificant(i)
+			if lastSpace || (next < len(tokens) && ")" == tokens[next].text) {
+				continue
+			}
+			if !strings.ContainsAny(tok.text, "\r\n") {
+				tok.text = " "
+			}
+			lastSpace = true
+		} else {
+			lastSpace = false
+		}
+		sb.WriteString(tok.text)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// namedData converts data of NamedQueryStruct into maps, bulk reports whether data is a slice
+func namedData(data interface{}) (items []map[string]interface{}, bulk bool, err error) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct:
+		m, err := namedItem(v)
+		if nil != err {
+			return nil, false, err
+		}
+		return []map[string]interface{}{m}, false, nil
+	case reflect.Slice, reflect.Array:
+		if 0 == v.Len() {
+			return nil, true, errNamedEmptyBulk
+		}
+		items = make([]map[string]interface{}, v.Len())
+		for i := range items {
+			items[i], err = namedItem(reflect.Indirect(v.Index(i)))
+			if nil != err {
+				return nil, true, err
+			}
+		}
+		return items, true, nil
+	}
+	return nil, false, errNamedDataType
+}
+
+func namedItem(v reflect.Value) (map[string]interface{}, error) {
+	if v.Kind() == reflect.Interface {
+		v = reflect.Indirect(v.Elem())
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, errNamedDataType
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m, nil
+	case reflect.Struct:
+		m := make(map[string]interface{}, v.NumField())
+		structToNamedMap(v, m)
+		return m, nil
+	}
+	return nil, errNamedDataType
+}
+
+// structToNamedMap collects the exported fields of v keyed by the ddb tag or the field name,
+// fields of embedded structs are collected as if they were v's
+func structToNamedMap(v reflect.Value, m map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			structToNamedMap(v.Field(i), m)
+			continue
+		}
+		if "" != field.PkgPath {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup(namedTagName); ok {
+			if idx := strings.IndexByte(tag, ','); idx >= 0 {
+				tag = tag[:idx]
+			}
+			if "-" == tag {
+				continue
+			}
+			if "" != tag {
+				name = tag
+			}
+		}
+		m[name] = v.Field(i).Interface()
+	}
+}
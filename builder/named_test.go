@@ -0,0 +1,414 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedQuerySyntax(t *testing.T) {
+	var testData = []struct {
+		sql  string
+		data map[string]interface{}
+		cond string
+		vals []interface{}
+		err  error
+	}{
+		{
+			sql:  `select * from tb where name=:name and age in :age and score>{{score}}`,
+			data: map[string]interface{}{"name": "caibirdme", "age": []int{1, 2}, "score": 60},
+			cond: `select * from tb where name=? and age in (?,?) and score>?`,
+			vals: []interface{}{"caibirdme", 1, 2, 60},
+		},
+		{
+			sql:  `select * from tb where name=@name and id=@id`,
+			data: map[string]interface{}{"name": "caibirdme"},
+			cond: `select * from tb where name=? and id=@id`,
+			vals: []interface{}{"caibirdme"},
+		},
+		{
+			sql:  "select ':name', \"{{name}}\", `:name`, 'it''s :name', 'a\\' :name' from tb where name=:name",
+			data: map[string]interface{}{"name": "caibirdme"},
+			cond: "select ':name', \"{{name}}\", `:name`, 'it''s :name', 'a\\' :name' from tb where name=?",
+			vals: []interface{}{"caibirdme"},
+		},
+		{
+			sql:  "select a::text, @@session.sql_mode, @x:=1 from tb -- where :name\n# :name\nwhere /* :name */ name=:name",
+			data: map[string]interface{}{"name": "caibirdme"},
+			cond: "select a::text, @@session.sql_mode, @x:=1 from tb -- where :name\n# :name\nwhere /* :name */ name=?",
+			vals: []interface{}{"caibirdme"},
+		},
+		{
+			sql:  `select * from tb where a=:a and b=:b`,
+			data: map[string]interface{}{"a": 1},
+			err:  errors.New("b not found"),
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testData {
+		cond, vals, err := NamedQuery(tc.sql, tc.data)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+type namedBase struct {
+	ID int `ddb:"id"`
+}
+
+type namedUser struct {
+	namedBase
+	Name    string `ddb:"name,omitempty"`
+	Age     int
+	Ignored string `ddb:"-"`
+	private int
+}
+
+func TestNamedQueryStruct(t *testing.T) {
+	var testData = []struct {
+		sql  string
+		data interface{}
+		cond string
+		vals []interface{}
+		err  error
+	}{
+		{
+			sql:  `select * from tb where id=:id and name=:name and age>:Age`,
+			data: namedUser{namedBase: namedBase{ID: 1}, Name: "caibirdme", Age: 18},
+			cond: `select * from tb where id=? and name=? and age>?`,
+			vals: []interface{}{1, "caibirdme", 18},
+		},
+		{
+			sql:  `select * from tb where id=:id`,
+			data: &namedUser{namedBase: namedBase{ID: 2}},
+			cond: `select * from tb where id=?`,
+			vals: []interface{}{2},
+		},
+		{
+			sql:  `select * from tb where id={{Ignored}}`,
+			data: namedUser{Ignored: "x"},
+			err:  errors.New("Ignored not found"),
+		},
+		{
+			sql: `INSERT INTO tb (id,name) VALUES (:id, :name) ON DUPLICATE KEY UPDATE name=VALUES(name)`,
+			data: []namedUser{
+				{namedBase: namedBase{ID: 1}, Name: "a"},
+				{namedBase: namedBase{ID: 2}, Name: "b"},
+			},
+			cond: `INSERT INTO tb (id,name) VALUES (?, ?),(?, ?) ON DUPLICATE KEY UPDATE name=VALUES(name)`,
+			vals: []interface{}{1, "a", 2, "b"},
+		},
+		{
+			sql: `insert into tb (id, tags) values(:id, concat(:tag, '(x)'))`,
+			data: []map[string]interface{}{
+				{"id": 1, "tag": "a"},
+				{"id": 2, "tag": "b"},
+			},
+			cond: `insert into tb (id, tags) values(?, concat(?, '(x)')),(?, concat(?, '(x)'))`,
+			vals: []interface{}{1, "a", 2, "b"},
+		},
+		{
+			sql:  `insert into tb (id) values (:id) on duplicate key update id=:id`,
+			data: []map[string]interface{}{{"id": 1}},
+			err:  errNamedBulkParam,
+		},
+		{
+			sql:  `update tb set id=:id`,
+			data: []map[string]interface{}{{"id": 1}},
+			err:  errNamedBulkValues,
+		},
+		{
+			sql:  `insert into tb (id) values (:id)`,
+			data: []namedUser{},
+			err:  errNamedEmptyBulk,
+		},
+		{
+			sql:  `select * from tb where id=:id`,
+			data: 1,
+			err:  errNamedDataType,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testData {
+		cond, vals, err := NamedQueryStruct(tc.sql, tc.data)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestNamedQueryOptionalBlock(t *testing.T) {
+	var testData = []struct {
+		sql  string
+		data map[string]interface{}
+		cond string
+		vals []interface{}
+		err  error
+	}{
+		{
+			sql:  `select * from tb where [[name=:name]] [[and age>:age]] order by id`,
+			data: map[string]interface{}{"age": 18},
+			cond: `select * from tb where age>? order by id`,
+			vals: []interface{}{18},
+		},
+		{
+			sql:  `select * from tb where [[name=:name]] [[and age>:age]] order by id`,
+			data: map[string]interface{}{"name": "", "age": nil},
+			cond: `select * from tb order by id`,
+			vals: nil,
+		},
+		{
+			sql:  `select * from tb where [[name=:name and]] [[age in :age]]`,
+			data: map[string]interface{}{"name": "caibirdme", "age": []int{}},
+			cond: `select * from tb where name=?`,
+			vals: []interface{}{"caibirdme"},
+		},
+		{
+			sql:  `select * from tb where (1=1 [[or a=:a]]) [[and (b=:b [[or c=:c]])]]`,
+			data: map[string]interface{}{"b": 0},
+			cond: `select * from tb where (1=1) and (b=?)`,
+			vals: []interface{}{0},
+		},
+		{
+			sql:  `select * from tb where ([[a=:a]]) and b=:b`,
+			data: map[string]interface{}{"b": 1},
+			cond: `select * from tb where b=?`,
+			vals: []interface{}{1},
+		},
+		{
+			sql:  `select * from tb where b=:b or (([[a=:a]])) and created_at<now()`,
+			data: map[string]interface{}{"b": 1},
+			cond: `select * from tb where b=? or created_at<now()`,
+			vals: []interface{}{1},
+		},
+		{
+			sql:  `select * from tb where ([[a=:a]] [[or c=:c]])`,
+			data: map[string]interface{}{"a": 1},
+			cond: `select * from tb where (a=?)`,
+			vals: []interface{}{1},
+		},
+		{
+			sql:  `select * from tb where b=:b and not ([[a=:a]])`,
+			data: map[string]interface{}{"b": 1},
+			cond: `select * from tb where b=?`,
+			vals: []interface{}{1},
+		},
+		{
+			sql:  `select * from tb where [[name=:name]]`,
+			data: nil,
+			cond: `select * from tb`,
+			vals: nil,
+		},
+		{
+			sql: `select * from tb where {{cond}} and [[:extra]] and id=:id`,
+			data: map[string]interface{}{
+				"cond":  map[string]interface{}{"age >": 18, "name": "caibirdme"},
+				"extra": Custom("score>?", 60),
+				"id":    1,
+			},
+			cond: `select * from tb where (name=? AND age>?) and (score>?) and id=?`,
+			vals: []interface{}{"caibirdme", 18, 60, 1},
+		},
+		{
+			sql: `select * from tb where :cond [[and :extra]]`,
+			data: map[string]interface{}{
+				"cond":  map[string]interface{}{},
+				"extra": Eq{},
+			},
+			cond: `select * from tb where 1=1`,
+			vals: nil,
+		},
+		{
+			sql:  `select * from tb where :cond`,
+			data: map[string]interface{}{"cond": map[string]interface{}{"a unknown": 1}},
+			err:  ErrUnsupportedOperator,
+		},
+		{
+			sql:  `select * from tb where [[name=:name`,
+			data: map[string]interface{}{"name": "caibirdme"},
+			err:  errNamedBlock,
+		},
+		{
+			sql:  `select * from tb where name=:name]]`,
+			data: map[string]interface{}{"name": "caibirdme"},
+			err:  errNamedBlock,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testData {
+		cond, vals, err := NamedQuery(tc.sql, tc.data)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestNamedQueryValues(t *testing.T) {
+	var testData = []struct {
+		sql  string
+		data map[string]interface{}
+		cond string
+		vals []interface{}
+		err  error
+	}{
+		{
+			sql:  `select * from tb where a={{a}} and b=:b and c=:c and d={{a}}`,
+			data: map[string]interface{}{"b": 1},
+			err:  errors.New("a,c not found"),
+		},
+		{
+			sql:  `select * from tb where a={{a}}`,
+			data: nil,
+			err:  errors.New("a not found"),
+		},
+		{
+			sql:  `select * from tb where name='{{name}}' -- {{name}}` + "\n" + `and id in {{ids}}`,
+			data: map[string]interface{}{"ids": []int{}},
+			err:  errors.New(`[builder] the value of "ids" must contain at least one element`),
+		},
+		{
+			sql:  `select * from tb where id in {{ids}}`,
+			data: map[string]interface{}{"ids": []int(nil)},
+			err:  errors.New(`[builder] the value of "ids" must contain at least one element`),
+		},
+		{
+			sql:  `update tb set a={{a}}, b={{b}}`,
+			data: map[string]interface{}{"a": nil, "b": []byte("xx")},
+			cond: `update tb set a=?, b=?`,
+			vals: []interface{}{nil, []byte("xx")},
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range testData {
+		cond, vals, err := NamedQuery(tc.sql, tc.data)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestNamedTemplateCache(t *testing.T) {
+	ass := assert.New(t)
+	sql := `select * from tb where id=:id`
+	t1, err := getNamedTemplate(sql)
+	ass.NoError(err)
+	t2, err := getNamedTemplate(sql)
+	ass.NoError(err)
+	ass.True(t1 == t2)
+	_, err = getNamedTemplate(`select [[`)
+	ass.Equal(errNamedBlock, err)
+}
+
+func TestCompile(t *testing.T) {
+	ass := assert.New(t)
+	tpl, err := Compile(`select * from tb where [[name=:name and]] age in {{age}}`)
+	ass.NoError(err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cond, vals, err := tpl.Bind(map[string]interface{}{"age": []int{i, i + 1}})
+			ass.NoError(err)
+			ass.Equal(`select * from tb where age in (?,?)`, cond)
+			ass.Equal([]interface{}{i, i + 1}, vals)
+		}(i)
+	}
+	wg.Wait()
+	cond, vals, err := tpl.BindStruct(struct {
+		Name string `ddb:"name"`
+		Age  []int  `ddb:"age"`
+	}{"caibirdme", []int{1}})
+	ass.NoError(err)
+	ass.Equal(`select * from tb where name=? and age in (?)`, cond)
+	ass.Equal([]interface{}{"caibirdme", 1}, vals)
+
+	_, err = Compile(`select [[`)
+	ass.Equal(errNamedBlock, err)
+	ass.Panics(func() { MustCompile(`select ]]`) })
+}
+
+var benchNamedSQL = `select a.name,a.age from tb1 as a join tb2 as b on a.id=b.id where a.age>{{age}} and b.age<{{foo}} and a.city in {{city}} order by a.name desc limit {{limit}}`
+
+func benchNamedData() map[string]interface{} {
+	return map[string]interface{}{
+		"age":   20,
+		"foo":   30,
+		"city":  []string{"beijing", "shanghai", "chengdu"},
+		"limit": 40,
+	}
+}
+
+var benchSearchHandle = regexp.MustCompile(`{{\S+?}}`)
+
+// namedQueryRegexp is the former regexp based implementation of NamedQuery, kept as the baseline of benchmarks
+func namedQueryRegexp(sql string, data map[string]interface{}) (string, []interface{}, error) {
+	vals := make([]interface{}, 0, len(data))
+	var err error
+	cond := benchSearchHandle.ReplaceAllStringFunc(sql, func(paramName string) string {
+		paramName = strings.TrimRight(strings.TrimLeft(paramName, "{"), "}")
+		val, ok := data[paramName]
+		if !ok {
+			err = fmt.Errorf("%s not found", paramName)
+			return ""
+		}
+		v := reflect.ValueOf(val)
+		if v.Type().Kind() != reflect.Slice {
+			vals = append(vals, val)
+			return paramPlaceHolder
+		}
+		length := v.Len()
+		for i := 0; i < length; i++ {
+			vals = append(vals, v.Index(i).Interface())
+		}
+		return createMultiPlaceholders(length)
+	})
+	if nil != err {
+		return "", nil, err
+	}
+	return cond, vals, nil
+}
+
+func BenchmarkNamedQuery_Regexp(b *testing.B) {
+	data := benchNamedData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		namedQueryRegexp(benchNamedSQL, data)
+	}
+}
+
+func BenchmarkNamedQuery(b *testing.B) {
+	data := benchNamedData()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NamedQuery(benchNamedSQL, data)
+	}
+}
+
+func BenchmarkTemplate_Bind(b *testing.B) {
+	data := benchNamedData()
+	tpl := MustCompile(benchNamedSQL)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tpl.Bind(data)
+	}
+}
+
+func BenchmarkTemplate_BindParallel(b *testing.B) {
+	tpl := MustCompile(benchNamedSQL)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		data := benchNamedData()
+		for pb.Next() {
+			tpl.Bind(data)
+		}
+	})
+}
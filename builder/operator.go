@@ -0,0 +1,161 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	errOperatorName     = errors.New("[builder] operator name can't be empty")
+	errOperatorTemplate = errors.New(`[builder] operator template must contain "%s" for the field`)
+
+	errOperatorExists   = `[builder] operator "%s" already exists`
+	errOperatorArity    = `[builder] operator "%s" has %d placeholders in template but arity is %d`
+	errOperatorValueLen = `[builder] the value of "%s" must be a slice of %d elements`
+)
+
+// Operator describes a where-map operator other than the ones supported natively
+type Operator struct {
+	// Name is what follows the field in the key, e.g. "regexp" in "name regexp", case-insensitive
+	Name string
+	// Arity is the number of placeholders in Template. 0 means the value is ignored,
+	// 1 binds the value itself and n > 1 requires the value to be a slice of n elements
+	Arity int
+	// Template is the condition with %s standing for the field, e.g. "%s REGEXP ?"
+	Template string
+}
+
+func (o Operator) checkValue(val interface{}) error {
+	if o.Arity <= 1 {
+		return nil
+	}
+	vals, ok := convertInterfaceToMap(val)
+	if !ok || len(vals) != o.Arity {
+		return fmt.Errorf(errOperatorValueLen, o.Name, o.Arity)
+	}
+	return nil
+}
+
+func (o Operator) build(field string, val interface{}) (string, []interface{}) {
+	cond := strings.Replace(o.Template, "%s", quoteField(field), -1)
+	switch o.Arity {
+	case 0:
+		return cond, nil
+	case 1:
+		return cond, []interface{}{val}
+	}
+	vals, _ := convertInterfaceToMap(val)
+	return cond, vals
+}
+
+var (
+	operatorLock  sync.RWMutex
+	operators     = make(map[string]Operator)
+	operatorOrder []string
+)
+
+func init() {
+	for _, op := range []Operator{
+		{Name: "regexp", Arity: 1, Template: "%s REGEXP ?"},
+		{Name: "not regexp", Arity: 1, Template: "%s NOT REGEXP ?"},
+		{Name: "sounds like", Arity: 1, Template: "%s SOUNDS LIKE ?"},
+		{Name: "is true", Arity: 0, Template: "%s IS TRUE"},
+		{Name: "is not true", Arity: 0, Template: "%s IS NOT TRUE"},
+		{Name: "is false", Arity: 0, Template: "%s IS FALSE"},
+		{Name: "is not false", Arity: 0, Template: "%s IS NOT FALSE"},
+		// bit test, "flags &": 4 => (flags & ?) != 0
+		{Name: "&", Arity: 1, Template: "(%s & ?) != 0"},
+		// the path of -> and ->> can't be a placeholder, so JSON_EXTRACT is used instead.
+		// "doc ->>": []interface{}{"$.name", "bob"} => JSON_UNQUOTE(JSON_EXTRACT(doc,?))=?
+		{Name: "->>", Arity: 2, Template: "JSON_UNQUOTE(JSON_EXTRACT(%s,?))=?"},
+	} {
+		if err := RegisterOperator(op); nil != err {
+			panic(err)
+		}
+	}
+}
+
+// RegisterOperator adds an operator usable in where and _having,
+// the name can't conflict with any existing operator.
+// the conditions of registered operators come after the native ones, in the order of registration
+func RegisterOperator(op Operator) error {
+	op.Name = removeInnerSpace(strings.ToLower(strings.TrimSpace(op.Name)))
+	if "" == op.Name {
+		return errOperatorName
+	}
+	if !strings.Contains(op.Template, "%s") {
+		return errOperatorTemplate
+	}
+	if n := strings.Count(op.Template, "?"); n != op.Arity {
+		return fmt.Errorf(errOperatorArity, op.Name, n, op.Arity)
+	}
+	operatorLock.Lock()
+	defer operatorLock.Unlock()
+	if _, ok := operators[op.Name]; ok || isStringInSlice(op.Name, opOrder) {
+		return fmt.Errorf(errOperatorExists, op.Name)
+	}
+	operators[op.Name] = op
+	operatorOrder = append(operatorOrder, op.Name)
+	return nil
+}
+
+// unregisterOperator removes the operator registered by RegisterOperator, only for tests
+func unregisterOperator(name string) {
+	name = removeInnerSpace(strings.ToLower(strings.TrimSpace(name)))
+	operatorLock.Lock()
+	defer operatorLock.Unlock()
+	if _, ok := operators[name]; !ok {
+		return
+	}
+	delete(operators, name)
+	for i, n := range operatorOrder {
+		if n == name {
+			operatorOrder = append(operatorOrder[:i:i], operatorOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+func lookupOperator(name string) (Operator, bool) {
+	operatorLock.RLock()
+	op, ok := operators[name]
+	operatorLock.RUnlock()
+	return op, ok
+}
+
+func registeredOperators() []Operator {
+	operatorLock.RLock()
+	defer operatorLock.RUnlock()
+	ops := make([]Operator, len(operatorOrder))
+	for i, name := range operatorOrder {
+		ops[i] = operators[name]
+	}
+	return ops
+}
+
+type operatorComparable struct {
+	op Operator
+	m  map[string]interface{}
+}
+
+// Build implements the Comparable interface
+func (o operatorComparable) Build() ([]string, []interface{}) {
+	if 0 == len(o.m) {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(o.m))
+	for k := range o.m {
+		keys = append(keys, k)
+	}
+	defaultSortAlgorithm(keys)
+	cond := make([]string, 0, len(keys))
+	var vals []interface{}
+	for _, k := range keys {
+		c, v := o.op.build(k, o.m[k])
+		cond = append(cond, c)
+		vals = append(vals, v...)
+	}
+	return cond, vals
+}
@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinOperators(t *testing.T) {
+	var data = []struct {
+		where map[string]interface{}
+		cond  string
+		vals  []interface{}
+		err   error
+	}{
+		{
+			where: map[string]interface{}{
+				"name REGEXP":      "^a",
+				"name not  regexp": "b$",
+				"age >":            10,
+			},
+			cond: "SELECT * FROM tb WHERE (age>? AND name REGEXP ? AND name NOT REGEXP ?)",
+			vals: []interface{}{10, "^a", "b$"},
+		},
+		{
+			where: map[string]interface{}{
+				"name sounds like": "tom",
+				"active is true":   nil,
+				"deleted is false": nil,
+				"flags &":          4,
+			},
+			cond: "SELECT * FROM tb WHERE (name SOUNDS LIKE ? AND active IS TRUE AND deleted IS FALSE AND (flags & ?) != 0)",
+			vals: []interface{}{"tom", 4},
+		},
+		{
+			where: map[string]interface{}{
+				"doc ->>": []interface{}{"$.name", "bob"},
+			},
+			cond: "SELECT * FROM tb WHERE (JSON_UNQUOTE(JSON_EXTRACT(doc,?))=?)",
+			vals: []interface{}{"$.name", "bob"},
+		},
+		{
+			where: map[string]interface{}{
+				"doc ->>": "bob",
+			},
+			err: errors.New(`[builder] the value of "->>" must be a slice of 2 elements`),
+		},
+		{
+			where: map[string]interface{}{
+				"_groupby": "name",
+				"_having": map[string]interface{}{
+					"name regexp": "^a",
+				},
+			},
+			cond: "SELECT * FROM tb GROUP BY name HAVING (name REGEXP ?)",
+			vals: []interface{}{"^a"},
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := BuildSelect("tb", tc.where, nil)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestRegisterOperator(t *testing.T) {
+	ass := assert.New(t)
+	ass.Equal(errOperatorName, RegisterOperator(Operator{Name: " ", Template: "%s"}))
+	ass.Equal(errOperatorTemplate, RegisterOperator(Operator{Name: "foo", Template: "?"}))
+	ass.Equal(errors.New(`[builder] operator "foo" has 1 placeholders in template but arity is 2`), RegisterOperator(Operator{Name: "foo", Arity: 2, Template: "%s=?"}))
+	ass.Equal(errors.New(`[builder] operator "like" already exists`), RegisterOperator(Operator{Name: "LIKE", Arity: 1, Template: "%s LIKE ?"}))
+	ass.Equal(errors.New(`[builder] operator "regexp" already exists`), RegisterOperator(Operator{Name: "regexp", Arity: 1, Template: "%s RLIKE ?"}))
+
+	ass.NoError(RegisterOperator(Operator{Name: "Test  Within", Arity: 2, Template: "%s BETWEEN ? - ? AND %s"}))
+	defer unregisterOperator("test within")
+	cond, vals, err := BuildUpdate("tb", map[string]interface{}{
+		"id":                1,
+		"score test within": []int{10, 3},
+	}, map[string]interface{}{"flag": 1})
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET flag=? WHERE (id=? AND score BETWEEN ? - ? AND score)", cond)
+	ass.Equal([]interface{}{1, 1, 10, 3}, vals)
+}
@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AllowFullTable is the key of where which lets BuildSafeUpdate and BuildSafeDelete
+// affect the whole table, usage where := map[string]interface{}{builder.AllowFullTable: true}
+const AllowFullTable = "_allowFullTable"
+
+var (
+	errAllowFullTableType = errors.New(`[builder] the value of "` + AllowFullTable + `" must be of bool type`)
+
+	errSafeNoWhere = `[builder] %s of table "%s" has no WHERE, set "` + AllowFullTable + `" if it's intended`
+	errSafeNoLimit = `[builder] %s of table "%s" has no LIMIT while the table has about %d rows, set "` + AllowFullTable + `" if it's intended`
+)
+
+// SetRowEstimate sets the estimated number of rows of table,
+// which is compared with the threshold of SetMaxRowsWithoutLimit, rows <= 0 removes the estimate.
+// It can be refreshed periodically, e.g. from information_schema.TABLES, while statements are built
+func SetRowEstimate(table string, rows int64) {
+	updateConfig(func(c *config) {
+		estimates := make(map[string]int64, len(c.rowEstimates)+1)
+		for t, n := range c.rowEstimates {
+			estimates[t] = n
+		}
+		if rows <= 0 {
+			delete(estimates, table)
+		} else {
+			estimates[table] = rows
+		}
+		c.rowEstimates = estimates
+	})
+}
+
+// SetMaxRowsWithoutLimit makes BuildSafeUpdate and BuildSafeDelete require _limit for tables
+// estimated to have more than n rows, n <= 0 disables it
+func SetMaxRowsWithoutLimit(n int64) {
+	updateConfig(func(c *config) {
+		c.maxRowsWithoutLimit = n
+	})
+}
+
+// BuildSafeUpdate works like BuildUpdate but rejects an update without WHERE, including the one
+// whose conditions are all removed by OmitEmpty, or without _limit on a large table
+// (see SetMaxRowsWithoutLimit), unless where contains AllowFullTable: true
+func BuildSafeUpdate(table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
+	if err := checkSafeWhere("UPDATE", table, where); nil != err {
+		return "", nil, err
+	}
+	return BuildUpdate(table, where, update)
+}
+
+// BuildSafeDelete works like BuildDelete with the checks of BuildSafeUpdate
+func BuildSafeDelete(table string, where map[string]interface{}) (string, []interface{}, error) {
+	if err := checkSafeWhere("DELETE", table, where); nil != err {
+		return "", nil, err
+	}
+	return BuildDelete(table, where)
+}
+
+func checkSafeWhere(statement, table string, where map[string]interface{}) error {
+	if val, ok := where[AllowFullTable]; ok {
+		allow, ok := val.(bool)
+		if !ok {
+			return errAllowFullTableType
+		}
+		if allow {
+			return nil
+		}
+	}
+	// the scope checks its required fields, but its conditions don't count as a WHERE:
+	// a scope like deleted_at IS NULL matches almost every row
+	scoped, err := applyScope(table, where)
+	if nil != err {
+		return err
+	}
+	conditions, err := getWhereConditions(where, defaultIgnoreKeys)
+	if nil != err {
+		return err
+	}
+	// a condition may render nothing, e.g. an empty Eq in _custom_ or an _or of empty maps
+	if whereString, _ := whereConnector("AND", conditions...); "" == whereString {
+		return fmt.Errorf(errSafeNoWhere, statement, table)
+	}
+	limit, err := getLimit(scoped)
+	if nil != err {
+		return err
+	}
+	c := loadConfig()
+	if estimate := c.rowEstimates[strings.TrimSpace(table)]; 0 == limit && c.maxRowsWithoutLimit > 0 && estimate > c.maxRowsWithoutLimit {
+		return fmt.Errorf(errSafeNoLimit, statement, table, estimate)
+	}
+	return nil
+}
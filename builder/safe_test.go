@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSafeUpdateDelete(t *testing.T) {
+	SetRowEstimate("big", 1000000)
+	SetMaxRowsWithoutLimit(10000)
+	defer SetMaxRowsWithoutLimit(0)
+	defer SetRowEstimate("big", 0)
+	update := map[string]interface{}{"status": 1}
+	var data = []struct {
+		build func() (string, []interface{}, error)
+		cond  string
+		err   error
+	}{
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeUpdate("tb", map[string]interface{}{"id": 1}, update)
+			},
+			cond: "UPDATE tb SET status=? WHERE (id=?)",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeUpdate("tb", nil, update)
+			},
+			err: errors.New(`[builder] UPDATE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				where := OmitEmpty(map[string]interface{}{"name": "", "_limit": 10}, []string{"name"})
+				return BuildSafeDelete("tb", where)
+			},
+			err: errors.New(`[builder] DELETE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("tb", map[string]interface{}{"_custom_1": Eq{}})
+			},
+			err: errors.New(`[builder] DELETE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("tb", map[string]interface{}{
+					"_or": []map[string]interface{}{{"_custom_1": Eq{}}, {"_custom_2": In{}}},
+				})
+			},
+			err: errors.New(`[builder] DELETE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeUpdate("tb", map[string]interface{}{"_custom_1": Eq{}, "id": 1}, update)
+			},
+			cond: "UPDATE tb SET status=? WHERE (id=?)",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("tb", map[string]interface{}{AllowFullTable: true})
+			},
+			cond: "DELETE FROM tb",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeUpdate("big", map[string]interface{}{"status": 0}, update)
+			},
+			err: errors.New(`[builder] UPDATE of table "big" has no LIMIT while the table has about 1000000 rows, set "_allowFullTable" if it's intended`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("big", map[string]interface{}{"status": 0, "_limit": 100})
+			},
+			cond: "DELETE FROM big WHERE (status=?) LIMIT ?",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("big", map[string]interface{}{"status": 0, AllowFullTable: true})
+			},
+			cond: "DELETE FROM big WHERE (status=?)",
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSafeDelete("tb", map[string]interface{}{AllowFullTable: "yes"})
+			},
+			err: errAllowFullTableType,
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, _, err := tc.build()
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+	}
+
+	// conditions of scope don't count
+	RegisterScope("tb", Scope{
+		Where:      map[string]interface{}{"deleted_at": IsNull},
+		SoftDelete: map[string]interface{}{"deleted_at": Raw("NOW()")},
+	})
+	defer RemoveScope("tb")
+	_, _, err := BuildSafeUpdate("tb", nil, update)
+	ass.EqualError(err, `[builder] UPDATE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`)
+	_, _, err = BuildSafeDelete("tb", map[string]interface{}{})
+	ass.EqualError(err, `[builder] DELETE of table "tb" has no WHERE, set "_allowFullTable" if it's intended`)
+	cond, _, err := BuildSafeDelete("tb", map[string]interface{}{"id": 1})
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET deleted_at=NOW() WHERE (id=? AND deleted_at IS NULL)", cond)
+	cond, _, err = BuildSafeDelete("tb", map[string]interface{}{AllowFullTable: true})
+	ass.NoError(err)
+	ass.Equal("UPDATE tb SET deleted_at=NOW() WHERE (deleted_at IS NULL)", cond)
+
+	// but its required fields and the row estimate apply
+	RegisterScope("big", Scope{Required: []string{"tenant_id"}})
+	defer RemoveScope("big")
+	_, _, err = BuildSafeDelete("big", map[string]interface{}{"id": 1})
+	ass.EqualError(err, `[builder] table "big" requires "tenant_id" in where`)
+	_, _, err = BuildSafeDelete("big", map[string]interface{}{"tenant_id": 1})
+	ass.EqualError(err, `[builder] DELETE of table "big" has no LIMIT while the table has about 1000000 rows, set "_allowFullTable" if it's intended`)
+}
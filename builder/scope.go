@@ -0,0 +1,105 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	errUnscopedValueType = errors.New(`[builder] the value of "_unscoped" must be of bool type`)
+
+	errScopeRequired = `[builder] table "%s" requires "%s" in where`
+)
+
+// Scope is the default conditions of a table, applied to every BuildSelect, BuildUpdate,
+// BuildBulkUpdate and BuildDelete of it unless where contains "_unscoped": true
+type Scope struct {
+	// Where is AND-ed with the where passed in, e.g. {"deleted_at": builder.IsNull}
+	Where map[string]interface{}
+	// Required are fields which must be compared by = or in in the where passed in, e.g. tenant_id
+	Required []string
+	// SoftDelete turns BuildDelete into an UPDATE setting it, e.g. {"deleted_at": builder.Raw("NOW()")}
+	SoftDelete map[string]interface{}
+}
+
+// RegisterScope sets the scope of table, which is matched with the table argument of builders exactly.
+// The scope is copied, so changing it afterwards has no effect, and it applies to
+// every statement of table built after RegisterScope returns, whichever package builds it
+func RegisterScope(table string, scope Scope) {
+	scope.Where = CloneWhere(scope.Where)
+	scope.Required = append([]string(nil), scope.Required...)
+	scope.SoftDelete = CloneWhere(scope.SoftDelete)
+	setScope(table, &scope)
+}
+
+// RemoveScope removes the scope of table, statements being built keep the scope they looked up
+func RemoveScope(table string) {
+	setScope(table, nil)
+}
+
+func setScope(table string, scope *Scope) {
+	updateConfig(func(c *config) {
+		scopes := make(map[string]Scope, len(c.scopes)+1)
+		for t, s := range c.scopes {
+			scopes[t] = s
+		}
+		if nil == scope {
+			delete(scopes, table)
+		} else {
+			scopes[table] = *scope
+		}
+		c.scopes = scopes
+	})
+}
+
+// lookupScope returns the scope of table unless where opts out of it
+func lookupScope(table string, where map[string]interface{}) (*Scope, error) {
+	if val, ok := where["_unscoped"]; ok {
+		unscoped, ok := val.(bool)
+		if !ok {
+			return nil, errUnscopedValueType
+		}
+		if unscoped {
+			return nil, nil
+		}
+	}
+	scope, ok := loadConfig().scopes[strings.TrimSpace(table)]
+	if !ok {
+		return nil, nil
+	}
+	return &scope, nil
+}
+
+// applyScope checks the required fields and adds the default conditions of table's scope to where
+func applyScope(table string, where map[string]interface{}) (map[string]interface{}, error) {
+	scope, err := lookupScope(table, where)
+	if nil != err || nil == scope {
+		return where, err
+	}
+	for _, field := range scope.Required {
+		if !hasEqualCondition(where, field) {
+			return nil, fmt.Errorf(errScopeRequired, table, field)
+		}
+	}
+	if 0 == len(scope.Where) {
+		return where, nil
+	}
+	return MergeWhere(MergeAnd, where, scope.Where)
+}
+
+func hasEqualCondition(where map[string]interface{}, field string) bool {
+	for k, v := range where {
+		if strings.HasPrefix(k, "_") {
+			continue
+		}
+		f, op, err := splitKey(k, v)
+		if nil != err || f != field {
+			continue
+		}
+		if op = strings.ToLower(op); opEq == op || opIn == op {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScope(t *testing.T) {
+	RegisterScope("user", Scope{
+		Where:      map[string]interface{}{"deleted_at": IsNull},
+		Required:   []string{"tenant_id"},
+		SoftDelete: map[string]interface{}{"deleted_at": Raw("NOW()")},
+	})
+	RegisterScope("log", Scope{Required: []string{"tenant_id"}})
+	defer RemoveScope("user")
+	defer RemoveScope("log")
+	var data = []struct {
+		build func() (string, []interface{}, error)
+		cond  string
+		vals  []interface{}
+		err   error
+	}{
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"tenant_id": 1, "age >": 10}, nil)
+			},
+			cond: "SELECT * FROM user WHERE (tenant_id=? AND age>? AND deleted_at IS NULL)",
+			vals: []interface{}{1, 10},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"tenant_id in": []int{1, 2}, "deleted_at": IsNull}, nil)
+			},
+			cond: "SELECT * FROM user WHERE (tenant_id IN (?,?) AND deleted_at IS NULL)",
+			vals: []interface{}{1, 2},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"age >": 10}, nil)
+			},
+			err: errors.New(`[builder] table "user" requires "tenant_id" in where`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"tenant_id >": 0}, nil)
+			},
+			err: errors.New(`[builder] table "user" requires "tenant_id" in where`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"age >": 10, "_unscoped": true}, nil)
+			},
+			cond: "SELECT * FROM user WHERE (age>?)",
+			vals: []interface{}{10},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("user", map[string]interface{}{"age >": 10, "_unscoped": 1}, nil)
+			},
+			err: errUnscopedValueType,
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildUpdate("user", map[string]interface{}{"tenant_id": 1, "id": 2}, map[string]interface{}{"name": "deen"})
+			},
+			cond: "UPDATE user SET name=? WHERE (id=? AND tenant_id=? AND deleted_at IS NULL)",
+			vals: []interface{}{"deen", 2, 1},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildDelete("user", map[string]interface{}{"tenant_id": 1, "id": 2})
+			},
+			cond: "UPDATE user SET deleted_at=NOW() WHERE (id=? AND tenant_id=? AND deleted_at IS NULL)",
+			vals: []interface{}{2, 1},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildDelete("user", map[string]interface{}{"id": 2, "_unscoped": true})
+			},
+			cond: "DELETE FROM user WHERE (id=?)",
+			vals: []interface{}{2},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildDelete("log", map[string]interface{}{"tenant_id": 1})
+			},
+			cond: "DELETE FROM log WHERE (tenant_id=?)",
+			vals: []interface{}{1},
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildBulkUpdate("log", "id", map[interface{}]map[string]interface{}{1: {"a": 1}}, nil)
+			},
+			err: errors.New(`[builder] table "log" requires "tenant_id" in where`),
+		},
+		{
+			build: func() (string, []interface{}, error) {
+				return BuildSelect("other", map[string]interface{}{"id": 1}, nil)
+			},
+			cond: "SELECT * FROM other WHERE (id=?)",
+			vals: []interface{}{1},
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := tc.build()
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
@@ -3,13 +3,16 @@ package builder
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 )
 
-// AggregateQuery is a helper function to execute the aggregate query and return the result
+// AggregateQuery is a helper function to execute the aggregate query and return the result,
+// with _groupby only the result of the last group is kept, use GroupedAggregateQuery for all groups
 func AggregateQuery(ctx context.Context, db *sql.DB, table string, where map[string]interface{}, aggregate AggregateSymbleBuilder) (ResultResolver, error) {
 	cond, vals, err := BuildSelect(table, where, []string{aggregate.Symble()})
 	if nil != err {
@@ -27,6 +30,167 @@ func AggregateQuery(ctx context.Context, db *sql.DB, table string, where map[str
 	return resultResolve{result}, err
 }
 
+var (
+	errGroupedAggregateArgs    = errors.New("[builder] grouped aggregate requires at least one group column and one aggregate")
+	errGroupedAggregateGroupBy = errors.New(`[builder] "_groupby" is not allowed in where of grouped aggregate, use groupBy instead`)
+)
+
+// AggregateGroup is a group of GroupedAggregateQuery
+type AggregateGroup struct {
+	keys    map[string]interface{}
+	symbols []string
+	values  []interface{}
+}
+
+// Key returns the value of the group column col, []byte is converted to string
+func (g AggregateGroup) Key(col string) interface{} {
+	return g.keys[col]
+}
+
+// Keys returns the values of all group columns keyed by column
+func (g AggregateGroup) Keys() map[string]interface{} {
+	return g.keys
+}
+
+// Get returns the result of aggregate, which must be one of those passed to the query
+func (g AggregateGroup) Get(aggregate AggregateSymbleBuilder) ResultResolver {
+	symble := aggregate.Symble()
+	for i, s := range g.symbols {
+		if s == symble {
+			return resultResolve{g.values[i]}
+		}
+	}
+	return resultResolve{0}
+}
+
+// Value returns the result of the i-th aggregate
+func (g AggregateGroup) Value(i int) ResultResolver {
+	if i < 0 || i >= len(g.values) {
+		return resultResolve{0}
+	}
+	return resultResolve{g.values[i]}
+}
+
+// GroupedAggregateQuery executes several aggregates grouped by groupBy, i.e.
+// SELECT groupBy...,aggregates... FROM table WHERE ... GROUP BY groupBy...,
+// fn is called for every group as the rows are read. where may contain _having but not _groupby
+func GroupedAggregateQuery(ctx context.Context, db *sql.DB, table string, where map[string]interface{}, groupBy []string, aggregates []AggregateSymbleBuilder, fn func(group AggregateGroup) error) error {
+	if 0 == len(groupBy) || 0 == len(aggregates) {
+		return errGroupedAggregateArgs
+	}
+	if _, ok := where["_groupby"]; ok {
+		return errGroupedAggregateGroupBy
+	}
+	symbols := make([]string, len(aggregates))
+	for i, ag := range aggregates {
+		symbols[i] = ag.Symble()
+	}
+	groupWhere := copyWhere(where)
+	groupWhere["_groupby"] = groupBy
+	fields := append(append(make([]string, 0, len(groupBy)+len(symbols)), groupBy...), symbols...)
+	cond, vals, err := BuildSelect(table, groupWhere, fields)
+	if nil != err {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, cond, vals...)
+	if nil != err {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		dest := make([]interface{}, len(fields))
+		ptrs := make([]interface{}, len(fields))
+		for i := range dest {
+			ptrs[i] = &dest[i]
+		}
+		if err = rows.Scan(ptrs...); nil != err {
+			return err
+		}
+		group := AggregateGroup{
+			keys:    make(map[string]interface{}, len(groupBy)),
+			symbols: symbols,
+			values:  dest[len(groupBy):],
+		}
+		for i, col := range groupBy {
+			if b, ok := dest[i].([]byte); ok {
+				dest[i] = string(b)
+			}
+			group.keys[col] = dest[i]
+		}
+		if err = fn(group); nil != err {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CollectGroupedAggregate works like GroupedAggregateQuery but returns all groups
+func CollectGroupedAggregate(ctx context.Context, db *sql.DB, table string, where map[string]interface{}, groupBy []string, aggregates []AggregateSymbleBuilder) ([]AggregateGroup, error) {
+	var groups []AggregateGroup
+	err := GroupedAggregateQuery(ctx, db, table, where, groupBy, aggregates, func(group AggregateGroup) error {
+		groups = append(groups, group)
+		return nil
+	})
+	if nil != err {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// QueryInChunks executes the queries built by BuildSelectChunks one by one,
+// fn is called once for every chunk to scan its rows, which are closed after fn returns
+func QueryInChunks(ctx context.Context, db *sql.DB, table string, where map[string]interface{}, selectField []string, fn func(rows *sql.Rows) error) error {
+	conds, chunkVals, err := BuildSelectChunks(table, where, selectField)
+	if nil != err {
+		return err
+	}
+	for i, cond := range conds {
+		rows, err := db.QueryContext(ctx, cond, chunkVals[i]...)
+		if nil != err {
+			return err
+		}
+		err = fn(rows)
+		if nil == err {
+			err = rows.Err()
+		}
+		rows.Close()
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimRows selects and locks at most n rows matching where inside tx, which is the usual way
+// for workers to take jobs from a queue table. The rows are locked with FOR UPDATE SKIP LOCKED
+// if the dialect supports LockNoWait, so that workers don't wait for each other, otherwise FOR UPDATE.
+// _lockMode of where takes precedence. fn is called to scan the rows, which are closed after fn returns,
+// and the locks are held until tx is committed or rolled back
+func ClaimRows(ctx context.Context, tx *sql.Tx, table string, where map[string]interface{}, selectField []string, n uint, fn func(rows *sql.Rows) error) error {
+	claimWhere := copyWhere(where)
+	claimWhere["_limit"] = []uint{n}
+	if _, ok := claimWhere["_lockMode"]; !ok {
+		claimWhere["_lockMode"] = "exclusive"
+		if loadConfig().dialect.LockNoWait {
+			claimWhere["_lockMode"] = "exclusive skip locked"
+		}
+	}
+	cond, vals, err := BuildSelect(table, claimWhere, selectField)
+	if nil != err {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, cond, vals...)
+	if nil != err {
+		return err
+	}
+	err = fn(rows)
+	if nil == err {
+		err = rows.Err()
+	}
+	rows.Close()
+	return err
+}
+
 // ResultResolver is a helper for retrieving data
 // caller should know the type and call the responding method
 type ResultResolver interface {
@@ -114,6 +278,22 @@ func AggregateMin(col string) AggregateSymbleBuilder {
 	return agBuilder("min(" + col + ")")
 }
 
+// GroupBy is the structured value of _groupby
+// usage where := map[string]interface{}{"_groupby": builder.GroupBy{Columns: []string{"year", "country"}, WithRollup: true}}
+type GroupBy struct {
+	// Columns are columns or expressions
+	Columns []string
+	// WithRollup adds the super-aggregate rows of GROUP BY ... WITH ROLLUP
+	WithRollup bool
+}
+
+// Grouping builds GROUPING(columns) for select fields, which is 1 in the super-aggregate rows
+// added by WithRollup for the columns being rolled up (MySQL 8.0+)
+// usage selectFields := []string{"year", builder.Grouping("year") + " AS g", "SUM(profit)"}
+func Grouping(columns ...string) string {
+	return "GROUPING(" + strings.Join(columns, ",") + ")"
+}
+
 // OmitEmpty is a helper function to clear where map zero value
 func OmitEmpty(where map[string]interface{}, omitKey []string) map[string]interface{} {
 	for _, key := range omitKey {
@@ -129,6 +309,108 @@ func OmitEmpty(where map[string]interface{}, omitKey []string) map[string]interf
 	return where
 }
 
+// OmitRule decides which values of a key are omitted by OmitEmptyWithRules
+type OmitRule int
+
+const (
+	// OmitZero omits zero values as OmitEmpty does
+	OmitZero OmitRule = iota
+	// OmitNil only omits nil, including nil pointers, maps and slices
+	OmitNil
+	// OmitEmptyString only omits ""
+	OmitEmptyString
+)
+
+func (r OmitRule) omit(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch r {
+	case OmitNil:
+		if !rv.IsValid() {
+			return true
+		}
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+			return rv.IsNil()
+		}
+		return false
+	case OmitEmptyString:
+		return rv.Kind() == reflect.String && 0 == rv.Len()
+	}
+	return isZero(rv)
+}
+
+// OmitEmptyWithRules is a non-mutating OmitEmpty, it returns a new where without the values omitted by
+// the rule of their keys, and the removed keys sorted.
+// A rule is looked up by the key as it is and then by its field, so a rule of "name" also covers "name like".
+// Maps in _or and _having are walked too, an _or branch left empty is removed and so is an _or without branches.
+// Removed keys in them are reported as paths like "_or[1].name" and "_having.total >".
+// An empty slice of a covered in / not in key is always omitted since it can't be built
+func OmitEmptyWithRules(where map[string]interface{}, rules map[string]OmitRule) (map[string]interface{}, []string) {
+	var removed []string
+	result := omitWhere(where, rules, "", &removed)
+	sort.Strings(removed)
+	return result, removed
+}
+
+func omitWhere(where map[string]interface{}, rules map[string]OmitRule, path string, removed *[]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(where))
+	for key, val := range where {
+		switch {
+		case strings.HasPrefix(key, "_or"):
+			orWheres, ok := val.([]map[string]interface{})
+			if !ok {
+				break
+			}
+			var branches []map[string]interface{}
+			for i, orWhere := range orWheres {
+				branch := omitWhere(orWhere, rules, fmt.Sprintf("%s%s[%d].", path, key, i), removed)
+				if 0 == len(branch) {
+					continue
+				}
+				branches = append(branches, branch)
+			}
+			if 0 == len(branches) {
+				*removed = append(*removed, path+key)
+				continue
+			}
+			val = branches
+		case "_having" == key:
+			if having, ok := val.(map[string]interface{}); ok {
+				having = omitWhere(having, rules, path+key+".", removed)
+				if 0 == len(having) {
+					*removed = append(*removed, path+key)
+					continue
+				}
+				val = having
+			}
+		case !strings.HasPrefix(key, "_"):
+			if omitWhereValue(key, val, rules) {
+				*removed = append(*removed, path+key)
+				continue
+			}
+		}
+		result[key] = val
+	}
+	return result
+}
+
+func omitWhereValue(key string, val interface{}, rules map[string]OmitRule) bool {
+	rule, ok := rules[key]
+	field, op, err := splitKey(key, val)
+	if !ok && nil == err {
+		rule, ok = rules[field]
+	}
+	if !ok {
+		return false
+	}
+	if op = strings.ToLower(op); nil == err && (opIn == op || opNotIn == op) {
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Slice && 0 == rv.Len() {
+			return true
+		}
+	}
+	return rule.omit(val)
+}
+
 type IsZeroer interface {
 	IsZero() bool
 }
@@ -190,6 +472,79 @@ func Custom(query string, args ...interface{}) Comparable {
 	return rawSql{sqlCond: query, values: args}
 }
 
+// UpdateExpr is a value of update map computing the new value from an expression
+type UpdateExpr struct {
+	// expr with %s standing for the field unless literal is set
+	expr    string
+	args    []interface{}
+	literal bool
+	// inserted means expr is a column of the row being inserted
+	inserted bool
+}
+
+func (u UpdateExpr) build(field string) (string, []interface{}) {
+	value, args := u.value(field)
+	return field + "=" + value, args
+}
+
+// value returns the new value of field without the assignment
+func (u UpdateExpr) value(field string) (string, []interface{}) {
+	if u.inserted {
+		if loadConfig().dialect.RowAlias {
+			return insertRowAlias + "." + quoteField(u.expr), nil
+		}
+		return "VALUES(" + quoteField(u.expr) + ")", nil
+	}
+	if u.literal {
+		return u.expr, u.args
+	}
+	return strings.Replace(u.expr, "%s", field, -1), u.args
+}
+
+// Incr increases the field by n
+// usage update := map[string]interface{}{"stock": builder.Incr(1)} => stock=stock+?
+func Incr(n interface{}) UpdateExpr {
+	return UpdateExpr{expr: "%s+?", args: []interface{}{n}}
+}
+
+// Decr decreases the field by n
+// usage update := map[string]interface{}{"stock": builder.Decr(1)} => stock=stock-?
+func Decr(n interface{}) UpdateExpr {
+	return UpdateExpr{expr: "%s-?", args: []interface{}{n}}
+}
+
+// Expr sets the field to an expression with bound args
+// usage update := map[string]interface{}{"price": builder.Expr("price*?+?", 1.1, 5)} => price=price*?+?
+func Expr(expr string, args ...interface{}) UpdateExpr {
+	return UpdateExpr{expr: expr, args: args, literal: true}
+}
+
+// Coalesce sets the field to val only if it's NULL
+// usage update := map[string]interface{}{"first_login": builder.Coalesce(now)} => first_login=COALESCE(first_login,?)
+func Coalesce(val interface{}) UpdateExpr {
+	return UpdateExpr{expr: "COALESCE(%s,?)", args: []interface{}{val}}
+}
+
+// Greatest sets the field to val only if val is greater
+// usage update := map[string]interface{}{"max_score": builder.Greatest(98)} => max_score=GREATEST(max_score,?)
+func Greatest(val interface{}) UpdateExpr {
+	return UpdateExpr{expr: "GREATEST(%s,?)", args: []interface{}{val}}
+}
+
+// Least sets the field to val only if val is less
+// usage update := map[string]interface{}{"min_price": builder.Least(10)} => min_price=LEAST(min_price,?)
+func Least(val interface{}) UpdateExpr {
+	return UpdateExpr{expr: "LEAST(%s,?)", args: []interface{}{val}}
+}
+
+// Inserted refers to the value of column col in the row being inserted,
+// it's only meaningful in the update of BuildInsertOnDuplicate.
+// usage update := map[string]interface{}{"name": builder.Inserted("name")}
+// => name=VALUES(name), or name=new.name if the dialect supports RowAlias
+func Inserted(col string) UpdateExpr {
+	return UpdateExpr{expr: col, inserted: true}
+}
+
 // JsonContains aim to check target json contains all items in given obj;if check certain value just use direct
 // where := map[string]interface{}{"your_json_field.'$.path_to_key' =": val}
 //
@@ -2,6 +2,8 @@ package builder
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"math"
 	"reflect"
 	"strconv"
@@ -141,6 +143,169 @@ func TestAggregateQuery(t *testing.T) {
 	}
 }
 
+func TestGroupedAggregateQuery(t *testing.T) {
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	ctx := context.Background()
+	mock.ExpectQuery("SELECT city,DATE\\(created_at\\),count\\(\\*\\),sum\\(price\\),avg\\(price\\) FROM orders WHERE \\(status=\\?\\) GROUP BY city,DATE\\(created_at\\) HAVING \\(count\\(\\*\\)>\\?\\)").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"city", "DATE(created_at)", "count(*)", "sum(price)", "avg(price)"}).
+			AddRow([]byte("bj"), "2020-01-01", int64(3), []byte("30"), float64(10)).
+			AddRow([]byte("sh"), "2020-01-01", int64(2), []byte("5"), float64(2.5)))
+	groups, err := CollectGroupedAggregate(ctx, db, "orders", map[string]interface{}{
+		"status":  1,
+		"_having": map[string]interface{}{"count(*) >": 1},
+	}, []string{"city", "DATE(created_at)"}, []AggregateSymbleBuilder{
+		AggregateCount("*"), AggregateSum("price"), AggregateAvg("price"),
+	})
+	ass.NoError(err)
+	ass.NoError(mock.ExpectationsWereMet())
+	ass.Len(groups, 2)
+	ass.Equal(map[string]interface{}{"city": "bj", "DATE(created_at)": "2020-01-01"}, groups[0].Keys())
+	ass.Equal("sh", groups[1].Key("city"))
+	ass.Equal(int64(3), groups[0].Get(AggregateCount("*")).Int64())
+	ass.Equal(int64(30), groups[0].Get(AggregateSum("price")).Int64())
+	ass.Equal(2.5, groups[1].Get(AggregateAvg("price")).Float64())
+	ass.Equal(int64(2), groups[1].Value(0).Int64())
+	ass.Equal(int64(0), groups[1].Get(AggregateMax("price")).Int64())
+	ass.Equal(int64(0), groups[1].Value(5).Int64())
+
+	// fn stops the iteration by returning an error
+	mock.ExpectQuery("SELECT city,count\\(\\*\\) FROM orders GROUP BY city").
+		WillReturnRows(sqlmock.NewRows([]string{"city", "count(*)"}).AddRow("bj", 1).AddRow("sh", 2))
+	var cities []interface{}
+	stop := errors.New("stop")
+	err = GroupedAggregateQuery(ctx, db, "orders", nil, []string{"city"}, []AggregateSymbleBuilder{AggregateCount("*")}, func(group AggregateGroup) error {
+		cities = append(cities, group.Key("city"))
+		return stop
+	})
+	ass.Equal(stop, err)
+	ass.Equal([]interface{}{"bj"}, cities)
+	ass.NoError(mock.ExpectationsWereMet())
+
+	_, err = CollectGroupedAggregate(ctx, db, "orders", nil, nil, []AggregateSymbleBuilder{AggregateCount("*")})
+	ass.Equal(errGroupedAggregateArgs, err)
+	_, err = CollectGroupedAggregate(ctx, db, "orders", map[string]interface{}{"_groupby": "city"}, []string{"city"}, []AggregateSymbleBuilder{AggregateCount("*")})
+	ass.Equal(errGroupedAggregateGroupBy, err)
+}
+
+func TestQueryInChunks(t *testing.T) {
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	SetInListLimit(2, InListChunk)
+	defer SetInListLimit(0, InListExpand)
+	mock.ExpectQuery("SELECT id FROM tb WHERE \\(id IN \\(\\?,\\?\\)\\)").WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery("SELECT id FROM tb WHERE \\(id IN \\(\\?\\)\\)").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	var ids []int
+	err = QueryInChunks(context.Background(), db, "tb", map[string]interface{}{"id": []int{1, 2, 3}}, []string{"id"}, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); nil != err {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	ass.NoError(err)
+	ass.Equal([]int{1, 2, 3}, ids)
+	ass.NoError(mock.ExpectationsWereMet())
+}
+
+func TestClaimRows(t *testing.T) {
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	SetDialect(MySQL8)
+	defer SetDialect(MySQL)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM job WHERE \\(status=\\?\\) ORDER BY id LIMIT \\?,\\? FOR UPDATE SKIP LOCKED").
+		WithArgs(0, 0, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4))
+	mock.ExpectCommit()
+	tx, err := db.Begin()
+	ass.NoError(err)
+	where := map[string]interface{}{"status": 0, "_orderby": "id"}
+	var ids []int
+	err = ClaimRows(context.Background(), tx, "job", where, []string{"id"}, 2, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); nil != err {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	ass.NoError(err)
+	ass.NoError(tx.Commit())
+	ass.Equal([]int{3, 4}, ids)
+	ass.Equal(map[string]interface{}{"status": 0, "_orderby": "id"}, where)
+	ass.NoError(mock.ExpectationsWereMet())
+}
+
+func TestOmitEmptyWithRules(t *testing.T) {
+	var nilPtr *int
+	where := map[string]interface{}{
+		"name like": "",
+		"age":       0,
+		"city":      nilPtr,
+		"score >":   0,
+		"id in":     []int{},
+		"tag":       "x",
+		"_or": []map[string]interface{}{
+			{"nick": "", "city": "bj"},
+			{"nick": ""},
+		},
+		"_or1": []map[string]interface{}{
+			{"nick": ""},
+		},
+		"_groupby": "name",
+		"_having": map[string]interface{}{
+			"total >": 0,
+		},
+		"_limit": []uint{10},
+	}
+	rules := map[string]OmitRule{
+		"name":    OmitEmptyString,
+		"nick":    OmitEmptyString,
+		"age":     OmitNil,
+		"city":    OmitNil,
+		"score >": OmitZero,
+		"id":      OmitNil,
+		"total >": OmitZero,
+	}
+	result, removed := OmitEmptyWithRules(where, rules)
+	ass := assert.New(t)
+	ass.Equal(map[string]interface{}{
+		"age": 0,
+		"tag": "x",
+		"_or": []map[string]interface{}{
+			{"city": "bj"},
+		},
+		"_groupby": "name",
+		"_limit":   []uint{10},
+	}, result)
+	ass.Equal([]string{
+		"_having",
+		"_having.total >",
+		"_or1",
+		"_or1[0].nick",
+		"_or[0].nick",
+		"_or[1].nick",
+		"city",
+		"id in",
+		"name like",
+		"score >",
+	}, removed)
+	// where is untouched
+	ass.Len(where, 11)
+	ass.Len(where["_or"].([]map[string]interface{})[0], 2)
+}
+
 func TestOmitEmpty(t *testing.T) {
 	var (
 		m  map[string]string
@@ -0,0 +1,91 @@
+package builder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const defaultVersionColumn = "version"
+
+var (
+	// ErrStaleVersion is returned by VersionedUpdate when no row matches the where and version,
+	// which means the row has been modified by others or doesn't exist
+	ErrStaleVersion = errors.New("[builder] stale version, the row has been modified or doesn't exist")
+
+	errVersionInWhere  = errors.New("[builder] the version column shouldn't be set in where of a versioned update")
+	errVersionInUpdate = errors.New("[builder] the version column shouldn't be set in update of a versioned update")
+)
+
+// SetVersionColumn sets the version column of table used by versioned updates, default is version,
+// an empty column restores the default. Tables can be configured concurrently
+func SetVersionColumn(table, column string) {
+	updateConfig(func(c *config) {
+		columns := make(map[string]string, len(c.versionColumns)+1)
+		for t, col := range c.versionColumns {
+			columns[t] = col
+		}
+		if "" == column {
+			delete(columns, table)
+		} else {
+			columns[table] = column
+		}
+		c.versionColumns = columns
+	})
+}
+
+func versionColumn(table string) string {
+	if col, ok := loadConfig().versionColumns[table]; ok {
+		return col
+	}
+	return defaultVersionColumn
+}
+
+// BuildVersionedUpdate works like BuildUpdate, and in addition it requires the version column
+// to equal version and increases it by one:
+// UPDATE table SET ...,version=version+? WHERE (... AND version=?)
+func BuildVersionedUpdate(table string, where map[string]interface{}, update map[string]interface{}, version interface{}) (string, []interface{}, error) {
+	col := versionColumn(table)
+	for k, v := range where {
+		if field, _, err := splitKey(k, v); nil == err && field == col {
+			return "", nil, errVersionInWhere
+		}
+	}
+	if _, ok := update[col]; ok {
+		return "", nil, errVersionInUpdate
+	}
+	versionedWhere := copyWhere(where)
+	versionedWhere[col] = version
+	versionedUpdate := make(map[string]interface{}, len(update)+1)
+	for k, v := range update {
+		versionedUpdate[k] = v
+	}
+	versionedUpdate[col] = Incr(1)
+	return BuildUpdate(table, versionedWhere, versionedUpdate)
+}
+
+// Execer is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// VersionedUpdate executes the update built by BuildVersionedUpdate,
+// ErrStaleVersion is returned if no row is affected
+func VersionedUpdate(ctx context.Context, db Execer, table string, where map[string]interface{}, update map[string]interface{}, version interface{}) error {
+	cond, vals, err := BuildVersionedUpdate(table, where, update, version)
+	if nil != err {
+		return err
+	}
+	res, err := db.ExecContext(ctx, cond, vals...)
+	if nil != err {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if nil != err {
+		return err
+	}
+	if 0 == affected {
+		return ErrStaleVersion
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildVersionedUpdate(t *testing.T) {
+	var data = []struct {
+		table   string
+		where   map[string]interface{}
+		update  map[string]interface{}
+		version interface{}
+		cond    string
+		vals    []interface{}
+		err     error
+	}{
+		{
+			table:   "tb",
+			where:   map[string]interface{}{"id": 1},
+			update:  map[string]interface{}{"name": "deen"},
+			version: 3,
+			cond:    "UPDATE tb SET name=?,version=version+? WHERE (id=? AND version=?)",
+			vals:    []interface{}{"deen", 1, 1, 3},
+		},
+		{
+			table:   "orders",
+			where:   map[string]interface{}{"id": 1},
+			update:  map[string]interface{}{"stock": Decr(1)},
+			version: int64(7),
+			cond:    "UPDATE orders SET revision=revision+?,stock=stock-? WHERE (id=? AND revision=?)",
+			vals:    []interface{}{1, 1, 1, int64(7)},
+		},
+		{
+			table:   "tb",
+			where:   map[string]interface{}{"id": 1, "version >": 2},
+			update:  map[string]interface{}{"name": "deen"},
+			version: 3,
+			err:     errVersionInWhere,
+		},
+		{
+			table:   "tb",
+			where:   map[string]interface{}{"id": 1},
+			update:  map[string]interface{}{"version": 4},
+			version: 3,
+			err:     errVersionInUpdate,
+		},
+	}
+	SetVersionColumn("orders", "revision")
+	defer SetVersionColumn("orders", "")
+	ass := assert.New(t)
+	for _, tc := range data {
+		cond, vals, err := BuildVersionedUpdate(tc.table, tc.where, tc.update, tc.version)
+		ass.Equal(tc.err, err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	ass := assert.New(t)
+	db, mock, err := sqlmock.New()
+	ass.NoError(err)
+	query := "UPDATE tb SET name=\\?,version=version\\+\\? WHERE \\(id=\\? AND version=\\?\\)"
+	mock.ExpectExec(query).WithArgs("deen", 1, 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs("deen", 1, 1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(query).WithArgs("deen", 1, 1, 3).WillReturnError(errors.New("bad conn"))
+	where := map[string]interface{}{"id": 1}
+	update := map[string]interface{}{"name": "deen"}
+	ass.NoError(VersionedUpdate(context.Background(), db, "tb", where, update, 3))
+	ass.Equal(ErrStaleVersion, VersionedUpdate(context.Background(), db, "tb", where, update, 3))
+	ass.EqualError(VersionedUpdate(context.Background(), db, "tb", where, update, 3), "bad conn")
+	ass.NoError(mock.ExpectationsWereMet())
+	// the maps of caller are untouched
+	ass.Equal(map[string]interface{}{"id": 1}, where)
+	ass.Equal(map[string]interface{}{"name": "deen"}, update)
+}
@@ -0,0 +1,213 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergePolicy decides what MergeWhere does when a key appears in more than one where map
+type MergePolicy int
+
+const (
+	// MergeError returns an error on collision
+	MergeError MergePolicy = iota
+	// MergeOverride keeps the value of the later map
+	MergeOverride
+	// MergeAnd keeps both conditions, which must be true at the same time
+	MergeAnd
+)
+
+var errMergeHavingType = errors.New(`[builder] the value of "_having" must be of map[string]interface{} type to be merged`)
+
+const (
+	errMergeCollision = `[builder] key "%s" exists in more than one where map`
+	errMergeAnd       = `[builder] "%s" can't be combined with AND, its values must be equal`
+)
+
+// MergeWhere merges wheres into a new map according to policy, the maps passed in are not modified.
+// Keys of the same field and operator collide even if they are written differently, e.g. "age" and "age =".
+// Equal values never collide. With MergeAnd, colliding conditions and _or are all kept,
+// _having maps are merged in the same way, and the other special keys like _limit, _orderby
+// can't be combined so their values must be equal
+func MergeWhere(policy MergePolicy, wheres ...map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	// normalized key => key in merged
+	keys := make(map[string]string)
+	seq := 0
+	for _, where := range wheres {
+		srcKeys := make([]string, 0, len(where))
+		for k := range where {
+			srcKeys = append(srcKeys, k)
+		}
+		defaultSortAlgorithm(srcKeys)
+		for _, k := range srcKeys {
+			v := where[k]
+			nk := normalizeWhereKey(k, v)
+			exist, ok := keys[nk]
+			if !ok {
+				keys[nk] = k
+				merged[k] = v
+				continue
+			}
+			if reflect.DeepEqual(merged[exist], v) {
+				continue
+			}
+			switch policy {
+			case MergeOverride:
+				delete(merged, exist)
+				keys[nk] = k
+				merged[k] = v
+				continue
+			case MergeAnd:
+			default:
+				return nil, fmt.Errorf(errMergeCollision, k)
+			}
+			switch {
+			case "_having" == nk:
+				a, ok1 := merged[exist].(map[string]interface{})
+				b, ok2 := v.(map[string]interface{})
+				if !ok1 || !ok2 {
+					return nil, errMergeHavingType
+				}
+				having, err := MergeWhere(MergeAnd, a, b)
+				if nil != err {
+					return nil, err
+				}
+				merged[exist] = having
+			case strings.HasPrefix(nk, "_or") || strings.HasPrefix(nk, "_custom_"):
+				prefix := "_or_merged"
+				if strings.HasPrefix(nk, "_custom_") {
+					prefix = "_custom_merged"
+				}
+				key := uniqueWhereKey(merged, prefix, &seq)
+				keys[key], merged[key] = key, v
+			case strings.HasPrefix(nk, "_"):
+				return nil, fmt.Errorf(errMergeAnd, k)
+			default:
+				// a single branch _or is just an AND-ed nested condition
+				key := uniqueWhereKey(merged, "_or_merged", &seq)
+				keys[key], merged[key] = key, []map[string]interface{}{{k: v}}
+			}
+		}
+	}
+	return merged, nil
+}
+
+// uniqueWhereKey returns a key for a condition AND-ed by MergeWhere, which must be registered
+// as a merged key so that a later where using it literally is AND-ed rather than replacing it
+func uniqueWhereKey(where map[string]interface{}, prefix string, seq *int) string {
+	for {
+		*seq++
+		key := prefix + strconv.Itoa(*seq)
+		if _, ok := where[key]; !ok {
+			return key
+		}
+	}
+}
+
+// normalizeWhereKey gives keys of the same field and operator the same form
+func normalizeWhereKey(key string, val interface{}) string {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "_") {
+		return key
+	}
+	field, op, err := splitKey(key, val)
+	if nil != err {
+		return key
+	}
+	return field + " " + strings.ToLower(op)
+}
+
+// CloneWhere returns a deep copy of where, nested maps and slices are copied
+// so that modifying the copy never affects where
+func CloneWhere(where map[string]interface{}) map[string]interface{} {
+	if nil == where {
+		return nil
+	}
+	return cloneWhereValue(reflect.ValueOf(where)).Interface().(map[string]interface{})
+}
+
+func cloneWhereValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := cloneWhereValue(v.Elem())
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c)
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), cloneWhereValue(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		// []byte is a scalar value
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(cloneWhereValue(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
+
+// WhereDiff is the result of DiffWhere, every slice is sorted
+type WhereDiff struct {
+	// Added are keys only in the map after
+	Added []string
+	// Removed are keys only in the map before
+	Removed []string
+	// Changed are keys in both maps with different values
+	Changed []string
+}
+
+// Empty reports whether the two maps are the same
+func (d WhereDiff) Empty() bool {
+	return 0 == len(d.Added) && 0 == len(d.Removed) && 0 == len(d.Changed)
+}
+
+// DiffWhere compares two where maps, keys of the same field and operator are the same key
+// even if they are written differently, e.g. "age" and "age =".
+// Keys are reported as they are written in after, or in before for Removed
+func DiffWhere(before, after map[string]interface{}) WhereDiff {
+	var diff WhereDiff
+	beforeKeys := make(map[string]string, len(before))
+	for k, v := range before {
+		beforeKeys[normalizeWhereKey(k, v)] = k
+	}
+	seen := make(map[string]struct{}, len(after))
+	for k, v := range after {
+		nk := normalizeWhereKey(k, v)
+		seen[nk] = struct{}{}
+		bk, ok := beforeKeys[nk]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, k)
+		case !reflect.DeepEqual(before[bk], v):
+			diff.Changed = append(diff.Changed, k)
+		}
+	}
+	for nk, k := range beforeKeys {
+		if _, ok := seen[nk]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
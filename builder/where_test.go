@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeWhere(t *testing.T) {
+	base := map[string]interface{}{
+		"tenant_id":  1,
+		"deleted_at": IsNull,
+		"_orderby":   "id desc",
+	}
+	var data = []struct {
+		policy MergePolicy
+		wheres []map[string]interface{}
+		cond   string
+		vals   []interface{}
+		err    error
+	}{
+		{
+			policy: MergeError,
+			wheres: []map[string]interface{}{base, {"age >": 10, "tenant_id": 1}},
+			cond:   "SELECT * FROM tb WHERE (tenant_id=? AND age>? AND deleted_at IS NULL) ORDER BY id desc",
+			vals:   []interface{}{1, 10},
+		},
+		{
+			policy: MergeError,
+			wheres: []map[string]interface{}{base, {"tenant_id =": 2}},
+			err:    errors.New(`[builder] key "tenant_id =" exists in more than one where map`),
+		},
+		{
+			policy: MergeOverride,
+			wheres: []map[string]interface{}{base, {"tenant_id =": 2, "_orderby": "age"}},
+			cond:   "SELECT * FROM tb WHERE (tenant_id=? AND deleted_at IS NULL) ORDER BY age",
+			vals:   []interface{}{2},
+		},
+		{
+			policy: MergeAnd,
+			wheres: []map[string]interface{}{
+				{"age >": 10, "_or": []map[string]interface{}{{"a": 1}, {"b": 2}}},
+				{"age  >": 20, "_or": []map[string]interface{}{{"c": 3}, {"d": 4}}},
+			},
+			cond: "SELECT * FROM tb WHERE (((a=?) OR (b=?)) AND ((c=?) OR (d=?)) AND ((age>?)) AND age>?)",
+			vals: []interface{}{1, 2, 3, 4, 20, 10},
+		},
+		{
+			policy: MergeAnd,
+			wheres: []map[string]interface{}{
+				{"_groupby": "name", "_having": map[string]interface{}{"total >": 1}},
+				{"_groupby": "name", "_having": map[string]interface{}{"total >": 2, "total <": 9}},
+			},
+			cond: "SELECT * FROM tb GROUP BY name HAVING (((total>?)) AND total>? AND total<?)",
+			vals: []interface{}{2, 1, 9},
+		},
+		{
+			policy: MergeAnd,
+			wheres: []map[string]interface{}{
+				{"_or": []map[string]interface{}{{"a": 1}, {"a": 2}}},
+				{"_or": []map[string]interface{}{{"b": 1}, {"b": 2}}},
+				{"_or_merged1": []map[string]interface{}{{"c": 1}, {"c": 2}}},
+			},
+			cond: "SELECT * FROM tb WHERE (((a=?) OR (a=?)) AND ((b=?) OR (b=?)) AND ((c=?) OR (c=?)))",
+			vals: []interface{}{1, 2, 1, 2, 1, 2},
+		},
+		{
+			policy: MergeAnd,
+			wheres: []map[string]interface{}{{"_limit": []uint{10}}, {"_limit": []uint{20}}},
+			err:    errors.New(`[builder] "_limit" can't be combined with AND, its values must be equal`),
+		},
+	}
+	ass := assert.New(t)
+	for _, tc := range data {
+		merged, err := MergeWhere(tc.policy, tc.wheres...)
+		ass.Equal(tc.err, err)
+		if nil != err {
+			continue
+		}
+		cond, vals, err := BuildSelect("tb", merged, nil)
+		ass.NoError(err)
+		ass.Equal(tc.cond, cond)
+		ass.Equal(tc.vals, vals)
+	}
+	ass.Len(base, 3)
+}
+
+func TestCloneWhere(t *testing.T) {
+	ass := assert.New(t)
+	where := map[string]interface{}{
+		"id in": []int{1, 2},
+		"_or": []map[string]interface{}{
+			{"a": 1},
+		},
+		"_having": map[string]interface{}{"total >": 1},
+		"data":    []byte("x"),
+		"name":    nil,
+	}
+	clone := CloneWhere(where)
+	ass.Equal(where, clone)
+	clone["id in"].([]int)[0] = 9
+	clone["_or"].([]map[string]interface{})[0]["a"] = 9
+	clone["_having"].(map[string]interface{})["total >"] = 9
+	ass.Equal([]int{1, 2}, where["id in"])
+	ass.Equal(1, where["_or"].([]map[string]interface{})[0]["a"])
+	ass.Equal(1, where["_having"].(map[string]interface{})["total >"])
+	ass.Nil(CloneWhere(nil))
+}
+
+func TestDiffWhere(t *testing.T) {
+	ass := assert.New(t)
+	diff := DiffWhere(map[string]interface{}{
+		"tenant_id": 1,
+		"age >":     10,
+		"_limit":    []uint{10},
+		"name":      "a",
+	}, map[string]interface{}{
+		"tenant_id =": 1,
+		"age  >":      20,
+		"_limit":      []uint{10},
+		"score <":     5,
+	})
+	ass.Equal(WhereDiff{
+		Added:   []string{"score <"},
+		Removed: []string{"name"},
+		Changed: []string{"age  >"},
+	}, diff)
+	ass.False(diff.Empty())
+	ass.True(DiffWhere(map[string]interface{}{"a": 1}, map[string]interface{}{"a =": 1}).Empty())
+}